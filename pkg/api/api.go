@@ -310,6 +310,7 @@ func (hs *HttpServer) registerRoutes() {
 		adminRoute.Put("/users/:id/quotas/:target", bind(m.UpdateUserQuotaCmd{}), wrap(UpdateUserQuota))
 		adminRoute.Get("/stats", AdminGetStats)
 		adminRoute.Post("/pause-all-alerts", bind(dtos.PauseAllAlertsCommand{}), wrap(PauseAllAlerts))
+		adminRoute.Post("/dashboards/upgrade-schema", bind(dtos.UpgradeDashboardSchemasCommand{}), wrap(UpgradeDashboardSchemas))
 	}, reqGrafanaAdmin)
 
 	// rendering
@@ -141,7 +141,7 @@ func PostDashboard(c *middleware.Context, cmd m.SaveDashboardCommand) Response {
 		UserId:    c.UserId,
 	}
 
-	dashboard, err := dashboards.GetRepository().SaveDashboard(dashItem)
+	dashboard, err := dashboards.GetRepository().SaveDashboard(c.Req.Context(), dashItem)
 
 	if err == m.ErrDashboardTitleEmpty {
 		return ApiError(400, m.ErrDashboardTitleEmpty.Error(), nil)
@@ -376,3 +376,66 @@ func GetDashboardTags(c *middleware.Context) {
 
 	c.JSON(200, query.Result)
 }
+
+const dashboardSchemaUpgradeBatchSize = 100
+
+// UpgradeDashboardSchemas upgrades the json of every dashboard in the org to
+// the latest schema version, one batch at a time. Upgraded dashboards drop
+// out of the query, so the first page is fetched until no batch makes progress.
+func UpgradeDashboardSchemas(c *middleware.Context, cmd dtos.UpgradeDashboardSchemasCommand) Response {
+	orgId := cmd.OrgId
+	if orgId == 0 {
+		orgId = c.OrgId
+	}
+
+	upgraded := 0
+	failed := make(map[int64]bool)
+
+	for {
+		query := m.GetDashboardsBelowSchemaVersionQuery{
+			OrgId:            orgId,
+			MaxSchemaVersion: dashboards.LatestSchemaVersion,
+			Page:             1,
+			Limit:            dashboardSchemaUpgradeBatchSize,
+		}
+
+		if err := bus.Dispatch(&query); err != nil {
+			return ApiError(500, "Failed to get dashboards", err)
+		}
+
+		saved := 0
+		for _, dash := range dashboards.UpgradeSchemas(query.Result) {
+			dashItem := &dashboards.SaveDashboardItem{
+				Dashboard: dash,
+				Message:   fmt.Sprintf("Upgraded to schema version %d", dashboards.LatestSchemaVersion),
+				OrgId:     orgId,
+				UserId:    c.UserId,
+			}
+
+			if _, err := dashboards.GetRepository().SaveDashboard(c.Req.Context(), dashItem); err != nil {
+				log.Warn("Failed to save upgraded dashboard %d, %s", dash.Id, err.Error())
+				failed[dash.Id] = true
+				continue
+			}
+
+			saved++
+		}
+
+		upgraded += saved
+		if saved == 0 || len(query.Result) < dashboardSchemaUpgradeBatchSize {
+			break
+		}
+	}
+
+	failedIds := make([]int64, 0)
+	for id := range failed {
+		failedIds = append(failedIds, id)
+	}
+
+	return Json(200, util.DynMap{
+		"message":       fmt.Sprintf("%d dashboards upgraded", upgraded),
+		"schemaVersion": dashboards.LatestSchemaVersion,
+		"upgraded":      upgraded,
+		"failed":        failedIds,
+	})
+}
@@ -52,9 +52,10 @@ type AlertTestResultLog struct {
 }
 
 type EvalMatch struct {
-	Tags   map[string]string `json:"tags,omitempty"`
-	Metric string            `json:"metric"`
-	Value  null.Float        `json:"value"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	Metric    string            `json:"metric"`
+	Value     null.Float        `json:"value"`
+	Threshold *float64          `json:"threshold,omitempty"`
 }
 
 type NotificationTestCommand struct {
@@ -47,3 +47,7 @@ type CalculateDiffTarget struct {
 type RestoreDashboardVersionCommand struct {
 	Version int `json:"version" binding:"Required"`
 }
+
+type UpgradeDashboardSchemasCommand struct {
+	OrgId int64 `json:"orgId"`
+}
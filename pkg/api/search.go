@@ -14,6 +14,7 @@ func Search(c *middleware.Context) {
 	tags := c.QueryStrings("tag")
 	starred := c.Query("starred")
 	limit := c.QueryInt("limit")
+	page := c.QueryInt("page")
 
 	if limit == 0 {
 		limit = 1000
@@ -29,9 +30,13 @@ func Search(c *middleware.Context) {
 
 	searchQuery := search.Query{
 		Title:        query,
+		TitleMatch:   search.TitleMatch(c.Query("titleMatch")),
 		Tags:         tags,
+		TagMatch:     search.TagMatch(c.Query("tagMatch")),
 		UserId:       c.UserId,
 		Limit:        limit,
+		Page:         page,
+		Sort:         search.SortOrder(c.Query("sort")),
 		IsStarred:    starred == "true",
 		OrgId:        c.OrgId,
 		DashboardIds: dbids,
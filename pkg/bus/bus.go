@@ -46,17 +46,7 @@ func (b *InProcBus) DispatchCtx(ctx context.Context, msg Msg) error {
 		return fmt.Errorf("handler not found for %s", msgName)
 	}
 
-	var params = make([]reflect.Value, 2)
-	params[0] = reflect.ValueOf(ctx)
-	params[1] = reflect.ValueOf(msg)
-
-	ret := reflect.ValueOf(handler).Call(params)
-	err := ret[0].Interface()
-	if err == nil {
-		return nil
-	} else {
-		return err.(error)
-	}
+	return callHandler(handler, ctx, msg)
 }
 
 func (b *InProcBus) Dispatch(msg Msg) error {
@@ -67,10 +57,21 @@ func (b *InProcBus) Dispatch(msg Msg) error {
 		return fmt.Errorf("handler not found for %s", msgName)
 	}
 
-	var params = make([]reflect.Value, 1)
-	params[0] = reflect.ValueOf(msg)
+	return callHandler(handler, context.Background(), msg)
+}
+
+// callHandler calls both plain handlers and handlers added with
+// AddCtxHandler, so a message can be dispatched with or without a context
+// regardless of how its handler was registered
+func callHandler(handler HandlerFunc, ctx context.Context, msg Msg) error {
+	handlerValue := reflect.ValueOf(handler)
+
+	var params = []reflect.Value{reflect.ValueOf(msg)}
+	if handlerValue.Type().NumIn() == 2 {
+		params = []reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(msg)}
+	}
 
-	ret := reflect.ValueOf(handler).Call(params)
+	ret := handlerValue.Call(params)
 	err := ret[0].Interface()
 	if err == nil {
 		return nil
@@ -1,6 +1,7 @@
 package bus
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"testing"
@@ -45,6 +46,41 @@ func TestQueryHandlerReturn(t *testing.T) {
 	}
 }
 
+func TestCtxHandlerCalledWithoutContext(t *testing.T) {
+	bus := New()
+
+	bus.AddCtxHandler(func(ctx context.Context, q *TestQuery) error {
+		if ctx == nil {
+			return errors.New("no context")
+		}
+		q.Resp = "hello from ctx handler"
+		return nil
+	})
+
+	query := &TestQuery{}
+	if err := bus.Dispatch(query); err != nil {
+		t.Fatal("Send query failed " + err.Error())
+	} else if query.Resp != "hello from ctx handler" {
+		t.Fatal("Failed to get response from ctx handler")
+	}
+}
+
+func TestHandlerCalledWithContext(t *testing.T) {
+	bus := New()
+
+	bus.AddHandler(func(q *TestQuery) error {
+		q.Resp = "hello from handler"
+		return nil
+	})
+
+	query := &TestQuery{}
+	if err := bus.DispatchCtx(context.Background(), query); err != nil {
+		t.Fatal("Send query failed " + err.Error())
+	} else if query.Resp != "hello from handler" {
+		t.Fatal("Failed to get response from handler")
+	}
+}
+
 func TestEventListeners(t *testing.T) {
 	bus := New()
 	count := 0
@@ -70,6 +70,7 @@ type Alert struct {
 	State          AlertStateType
 	Handler        int64
 	Silenced       bool
+	SilencedUntil  *time.Time
 	ExecutionError string
 	Frequency      int64
 
@@ -159,6 +160,17 @@ type SetAlertStateCommand struct {
 	Timestamp time.Time
 }
 
+type SilenceAlertCommand struct {
+	OrgId   int64
+	AlertId int64
+	Until   time.Time
+}
+
+type UnsilenceAlertCommand struct {
+	OrgId   int64
+	AlertId int64
+}
+
 type DeleteAlertCommand struct {
 	AlertId int64
 }
@@ -61,6 +61,15 @@ type GetDashboardVersionQuery struct {
 	Result *DashboardVersion
 }
 
+type GetDashboardsByVersionAndMessageQuery struct {
+	DashboardId     int64
+	OrgId           int64
+	Version         int
+	MessageFragment string
+
+	Result *DashboardVersion
+}
+
 type GetDashboardVersionsQuery struct {
 	DashboardId int64
 	OrgId       int64
@@ -74,5 +83,19 @@ type GetDashboardVersionsQuery struct {
 // Commands
 //
 
+type RestoreDashboardVersionCommand struct {
+	DashboardId int64
+	OrgId       int64
+	UserId      int64
+	Version     int
+
+	Result *Dashboard
+}
+
 type DeleteExpiredVersionsCommand struct {
+	// VersionsToKeep overrides the configured number of versions to keep
+	// per dashboard when set
+	VersionsToKeep int
+
+	DeletedRows int64
 }
@@ -2,6 +2,8 @@ package models
 
 import (
 	"errors"
+	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -18,6 +20,7 @@ var (
 	ErrDashboardTitleEmpty               = errors.New("Dashboard title cannot be empty")
 	ErrDashboardContainsInvalidAlertData = errors.New("Invalid alert data. Cannot save dashboard")
 	ErrDashboardFailedToUpdateAlertData  = errors.New("Failed to save alert data")
+	ErrDashboardTitleNotUnique           = errors.New("More than one dashboard matches the title")
 )
 
 type UpdatePluginDashboardError struct {
@@ -71,6 +74,143 @@ func (dash *Dashboard) GetTags() []string {
 	return dash.Data.Get("tags").MustStringArray()
 }
 
+// GetPanels returns the panels in data json, including the ones
+// nested in rows
+func (dash *Dashboard) GetPanels() []*simplejson.Json {
+	panels := make([]*simplejson.Json, 0)
+
+	for _, row := range dash.Data.Get("rows").MustArray() {
+		for _, panel := range simplejson.NewFromAny(row).Get("panels").MustArray() {
+			panels = append(panels, simplejson.NewFromAny(panel))
+		}
+	}
+
+	for _, p := range dash.Data.Get("panels").MustArray() {
+		panel := simplejson.NewFromAny(p)
+		panels = append(panels, panel)
+
+		// collapsed rows keep their panels
+		for _, nested := range panel.Get("panels").MustArray() {
+			panels = append(panels, simplejson.NewFromAny(nested))
+		}
+	}
+
+	return panels
+}
+
+// GetDatasourceRefs returns the names of the datasources used by the panels
+// and their queries, an empty name refers to the default datasource
+func (dash *Dashboard) GetDatasourceRefs() []string {
+	refs := make([]string, 0)
+	seen := make(map[string]bool)
+
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			refs = append(refs, name)
+		}
+	}
+
+	for _, panel := range dash.GetPanels() {
+		if panel.Get("type").MustString() == "row" {
+			continue
+		}
+
+		add(panel.Get("datasource").MustString())
+
+		for _, target := range panel.Get("targets").MustArray() {
+			if name := simplejson.NewFromAny(target).Get("datasource").MustString(); name != "" {
+				add(name)
+			}
+		}
+	}
+
+	return refs
+}
+
+// GetTemplateVariables returns the template variables in data json
+func (dash *Dashboard) GetTemplateVariables() []*simplejson.Json {
+	variables := make([]*simplejson.Json, 0)
+	for _, v := range dash.Data.Get("templating").Get("list").MustArray() {
+		variables = append(variables, simplejson.NewFromAny(v))
+	}
+	return variables
+}
+
+// HasAbsoluteTimeRange returns true when the time range in data json is
+// pinned to epoch or ISO timestamps instead of being relative to now
+func (dash *Dashboard) HasAbsoluteTimeRange() bool {
+	timeRange := dash.Data.Get("time")
+	return isAbsoluteTime(timeRange.Get("from")) && isAbsoluteTime(timeRange.Get("to"))
+}
+
+func isAbsoluteTime(value *simplejson.Json) bool {
+	if _, err := value.Float64(); err == nil {
+		return true
+	}
+
+	str := value.MustString()
+	if _, err := strconv.ParseInt(str, 10, 64); err == nil {
+		return true
+	}
+
+	_, err := time.Parse(time.RFC3339, str)
+	return err == nil
+}
+
+// GetRefreshInterval parses the auto-refresh interval in data json,
+// returns false when auto-refresh is off or the interval is unparsable
+func (dash *Dashboard) GetRefreshInterval() (time.Duration, bool) {
+	interval, err := parseInterval(dash.Data.Get("refresh").MustString())
+	if err != nil || interval <= 0 {
+		return 0, false
+	}
+
+	return interval, true
+}
+
+// GetMaxQueryTimeout returns the longest query timeout set on any panel,
+// timeouts are either interval strings or a number of seconds
+func (dash *Dashboard) GetMaxQueryTimeout() time.Duration {
+	max := time.Duration(0)
+
+	for _, panel := range dash.GetPanels() {
+		for _, value := range []*simplejson.Json{panel.Get("timeout"), panel.Get("options").Get("timeout")} {
+			var timeout time.Duration
+			if seconds, err := value.Float64(); err == nil {
+				timeout = time.Duration(seconds * float64(time.Second))
+			} else if parsed, err := parseInterval(value.MustString()); err == nil {
+				timeout = parsed
+			}
+
+			if timeout > max {
+				max = timeout
+			}
+		}
+	}
+
+	return max
+}
+
+// parseInterval parses interval strings like 10s, 5m or 1d, plain
+// numbers are seconds
+func parseInterval(interval string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(interval); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	// time.ParseDuration does not know about days
+	if strings.HasSuffix(interval, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(interval, "d"))
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	return time.ParseDuration(interval)
+}
+
 func NewDashboardFromJson(data *simplejson.Json) *Dashboard {
 	dash := &Dashboard{}
 	dash.Data = data
@@ -144,6 +284,26 @@ type SaveDashboardCommand struct {
 	UpdatedAt time.Time
 
 	Result *Dashboard
+	// PreviousVersion is the version replaced by the save, 0 for new dashboards
+	PreviousVersion int
+}
+
+type SaveDashboardsCommand struct {
+	Dashboards []*SaveDashboardCommand
+
+	Result []*Dashboard
+}
+
+// SaveDashboardsError tells which dashboard of a SaveDashboardsCommand
+// could not be saved
+type SaveDashboardsError struct {
+	Index int
+	Title string
+	Err   error
+}
+
+func (e SaveDashboardsError) Error() string {
+	return fmt.Sprintf("Failed to save dashboard %q: %v", e.Title, e.Err)
 }
 
 type DeleteDashboardCommand struct {
@@ -151,13 +311,19 @@ type DeleteDashboardCommand struct {
 	OrgId int64
 }
 
+type DeleteDashboardsCommand struct {
+	DashboardIds []int64
+	OrgId        int64
+}
+
 //
 // QUERIES
 //
 
 type GetDashboardQuery struct {
-	Slug  string // required if no Id is specified
+	Slug  string // required if no Id or Title is specified
 	Id    int64  // optional if slug is set
+	Title string // used only when both Slug and Id are empty
 	OrgId int64
 
 	Result *Dashboard
@@ -184,6 +350,418 @@ type GetDashboardsByPluginIdQuery struct {
 	Result   []*Dashboard
 }
 
+type GetDashboardsByTagCombinationsQuery struct {
+	OrgId   int64
+	TagSets [][]string // a dashboard matches when it has every tag of at least one set
+	Result  []*Dashboard
+}
+
+type GetDashboardsByAlertSeverityQuery struct {
+	OrgId    int64
+	Severity string
+	Result   []*Dashboard
+}
+
+type DashboardWithNotificationCount struct {
+	DashboardId       int64  `json:"dashboardId"`
+	Title             string `json:"title"`
+	Slug              string `json:"slug"`
+	NotificationCount int    `json:"notificationCount"`
+}
+
+type GetDashboardsByAlertNotificationCountQuery struct {
+	OrgId       int64
+	MinChannels int
+	Result      []*DashboardWithNotificationCount
+}
+
+type GetDashboardsByRefreshRateQuery struct {
+	OrgId             int64
+	MaxRefreshSeconds int
+	Result            []*Dashboard
+}
+
+type GetDashboardsByVariableWithDefaultQuery struct {
+	OrgId        int64
+	VariableName string
+	DefaultValue string
+	Result       []*Dashboard
+}
+
+type GetDashboardsByPinnedTimeRangeQuery struct {
+	OrgId  int64
+	Result []*Dashboard
+}
+
+type GetDashboardsBelowSchemaVersionQuery struct {
+	OrgId            int64
+	MaxSchemaVersion int
+	Page             int
+	Limit            int
+	Result           []*Dashboard
+}
+
+type GetDashboardsByDatasourceTypeQuery struct {
+	OrgId          int64
+	DatasourceType string
+	Result         []*Dashboard
+}
+
+type DashboardWithTagCount struct {
+	DashboardId int64  `json:"dashboardId"`
+	Title       string `json:"title"`
+	Slug        string `json:"slug"`
+	TagCount    int    `json:"tagCount"`
+}
+
+type GetDashboardsByTagCountQuery struct {
+	OrgId   int64
+	MinTags int
+	MaxTags int
+	Result  []*DashboardWithTagCount
+}
+
+type GetDashboardsByPanelPluginIdQuery struct {
+	OrgId         int64
+	PanelPluginId string
+	Result        []*Dashboard
+}
+
+type DashboardWithSilenceInfo struct {
+	DashboardId   int64     `json:"dashboardId"`
+	Title         string    `json:"title"`
+	SilencedUntil time.Time `json:"silencedUntil"`
+}
+
+type GetDashboardsWithSilencedAlertsQuery struct {
+	OrgId  int64
+	Result []*DashboardWithSilenceInfo
+}
+
+type GetDashboardsByAnnotationTagQuery struct {
+	OrgId  int64
+	Tag    string // either key or key:value
+	Result []*Dashboard
+}
+
+type GetDashboardsByQueryTimeoutQuery struct {
+	OrgId             int64
+	MinTimeoutSeconds int
+	Result            []*Dashboard
+}
+
+type DashboardWithVariableCount struct {
+	DashboardId   int64  `json:"dashboardId"`
+	Title         string `json:"title"`
+	Slug          string `json:"slug"`
+	VariableCount int    `json:"variableCount"`
+}
+
+type GetDashboardsByTemplateVariableCountQuery struct {
+	OrgId        int64
+	MinVariables int
+	Result       []*DashboardWithVariableCount
+}
+
+type DashboardWithAlertFrequency struct {
+	DashboardId  int64  `json:"dashboardId"`
+	Title        string `json:"title"`
+	Slug         string `json:"slug"`
+	MinFrequency int64  `json:"minFrequency"`
+}
+
+type GetDashboardsByAlertFrequencyQuery struct {
+	OrgId               int64
+	MaxFrequencySeconds int64
+	Result              []*DashboardWithAlertFrequency
+}
+
+type DashboardWithAnnotationInfo struct {
+	DashboardId         int64  `json:"dashboardId"`
+	Title               string `json:"title"`
+	Slug                string `json:"slug"`
+	LastAnnotationEpoch int64  `json:"lastAnnotationEpoch"`
+}
+
+type GetDashboardsByLastAnnotationQuery struct {
+	OrgId  int64
+	Since  time.Time
+	Result []*DashboardWithAnnotationInfo
+}
+
+type DashboardWithTagSummary struct {
+	DashboardId int64    `json:"dashboardId"`
+	Title       string   `json:"title"`
+	Slug        string   `json:"slug"`
+	Tags        []string `json:"tags"`
+}
+
+type GetDashboardsByOrgWithTagSummaryQuery struct {
+	OrgId  int64
+	Page   int
+	Limit  int
+	Result []*DashboardWithTagSummary
+}
+
+type DashboardWithBrokenDatasource struct {
+	DashboardId        int64    `json:"dashboardId"`
+	Title              string   `json:"title"`
+	Slug               string   `json:"slug"`
+	MissingDatasources []string `json:"missingDatasources"`
+}
+
+type GetDashboardsByNonExistentDatasourceQuery struct {
+	OrgId  int64
+	Result []*DashboardWithBrokenDatasource
+}
+
+type DashboardVersionMatch struct {
+	DashboardId    int64  `json:"dashboardId"`
+	DashboardTitle string `json:"dashboardTitle"`
+	Version        int    `json:"version"`
+	Message        string `json:"message"`
+}
+
+type GetDashboardsByVersionMessageKeywordQuery struct {
+	OrgId   int64
+	Keyword string
+	Result  []*DashboardVersionMatch
+}
+
+type DashboardWithAlertStatus struct {
+	DashboardId   int64  `json:"dashboardId"`
+	Title         string `json:"title"`
+	AlertingCount int    `json:"alertingCount"`
+	OKCount       int    `json:"okCount" xorm:"'ok_count'"`
+	NoDataCount   int    `json:"noDataCount"`
+	PendingCount  int    `json:"pendingCount"`
+}
+
+type GetDashboardsByOrgWithAlertStatusQuery struct {
+	OrgId  int64
+	Result []*DashboardWithAlertStatus
+}
+
+type DashboardWithPanelCount struct {
+	DashboardId int64  `json:"dashboardId"`
+	Title       string `json:"title"`
+	Slug        string `json:"slug"`
+	PanelCount  int    `json:"panelCount"`
+}
+
+type GetDashboardsByPanelCountQuery struct {
+	OrgId     int64
+	MinPanels int
+	MaxPanels int // 0 means no upper limit
+	Result    []*DashboardWithPanelCount
+}
+
+// GetDashboardsByRefreshIntervalQuery counts dashboards per raw refresh
+// value, dashboards without auto-refresh are counted under ""
+type GetDashboardsByRefreshIntervalQuery struct {
+	OrgId  int64
+	Result map[string]int
+}
+
+type DashboardWithTargetCount struct {
+	DashboardId    int64  `json:"dashboardId"`
+	Title          string `json:"title"`
+	Slug           string `json:"slug"`
+	TargetCount    int    `json:"targetCount"`
+	MaxPanelTarget int    `json:"maxPanelTarget"`
+}
+
+type GetDashboardsByQueryTargetCountQuery struct {
+	OrgId      int64
+	MinTargets int
+	Result     []*DashboardWithTargetCount
+}
+
+type DashboardWithAnnotationCount struct {
+	DashboardId     int64  `json:"dashboardId"`
+	Title           string `json:"title"`
+	Slug            string `json:"slug"`
+	AnnotationCount int    `json:"annotationCount"`
+}
+
+type GetDashboardsByAnnotationCountQuery struct {
+	OrgId          int64
+	MinAnnotations int
+	Result         []*DashboardWithAnnotationCount
+}
+
+type DashboardWithRowCount struct {
+	DashboardId int64  `json:"dashboardId"`
+	Title       string `json:"title"`
+	Slug        string `json:"slug"`
+	RowCount    int    `json:"rowCount"`
+}
+
+type GetDashboardsByRowCountQuery struct {
+	OrgId   int64
+	MinRows int
+	Result  []*DashboardWithRowCount
+}
+
+type GetDashboardsByHasAnnotationsQuery struct {
+	OrgId          int64
+	HasAnnotations bool
+	Result         []*Dashboard
+}
+
+type GetDashboardsByOrgWithAnnotationSummaryQuery struct {
+	OrgId  int64
+	Since  time.Time
+	Page   int
+	Limit  int
+	Result []*DashboardWithAnnotationCount
+}
+
+type DashboardWithRestoreCount struct {
+	DashboardId  int64  `json:"dashboardId"`
+	Title        string `json:"title"`
+	Slug         string `json:"slug"`
+	RestoreCount int    `json:"restoreCount"`
+}
+
+type GetDashboardsByVersionRestoreCountQuery struct {
+	OrgId       int64
+	MinRestores int
+	Result      []*DashboardWithRestoreCount
+}
+
+type GetDashboardsByHasPluginIdQuery struct {
+	OrgId       int64
+	HasPluginId bool
+	Result      []*Dashboard
+}
+
+type DashboardFullSummary struct {
+	DashboardId  int64     `json:"dashboardId"`
+	Title        string    `json:"title"`
+	Slug         string    `json:"slug"`
+	Version      int       `json:"version"`
+	Updated      time.Time `json:"updated"`
+	Tags         []string  `json:"tags"`
+	VersionCount int       `json:"versionCount"`
+	StarCount    int       `json:"starCount"`
+}
+
+type GetDashboardsByOrgWithFolderAndTagSummaryQuery struct {
+	OrgId  int64
+	Page   int
+	Limit  int
+	Result []*DashboardFullSummary
+}
+
+type DashboardWithNoDataCount struct {
+	DashboardId int64  `json:"dashboardId"`
+	Title       string `json:"title"`
+	Slug        string `json:"slug"`
+	NoDataCount int    `json:"noDataCount"`
+}
+
+type GetDashboardsByNoDataAlertCountQuery struct {
+	OrgId           int64
+	MinNoDataAlerts int
+	Result          []*DashboardWithNoDataCount
+}
+
+type DashboardWithQueryCount struct {
+	DashboardId int64  `json:"dashboardId"`
+	Title       string `json:"title"`
+	Slug        string `json:"slug"`
+	QueryCount  int    `json:"queryCount"`
+}
+
+type GetDashboardsByQueryCountRangeQuery struct {
+	OrgId      int64
+	MinQueries int
+	MaxQueries int // 0 means no upper limit
+	Result     []*DashboardWithQueryCount
+}
+
+type DashboardWithUserInfo struct {
+	DashboardId    int64     `json:"dashboardId"`
+	Title          string    `json:"title"`
+	Slug           string    `json:"slug"`
+	Created        time.Time `json:"created"`
+	Updated        time.Time `json:"updated"`
+	CreatedByLogin string    `json:"createdByLogin"`
+	CreatedByEmail string    `json:"createdByEmail"`
+	UpdatedByLogin string    `json:"updatedByLogin"`
+	UpdatedByEmail string    `json:"updatedByEmail"`
+}
+
+type GetDashboardsByOrgWithUserInfoQuery struct {
+	OrgId  int64
+	Page   int
+	Limit  int
+	Result []*DashboardWithUserInfo
+}
+
+type GetDashboardsByStarredAndTagQuery struct {
+	UserId int64
+	OrgId  int64
+	Tag    string
+	Result []*Dashboard
+}
+
+type DashboardWithSize struct {
+	DashboardId int64  `json:"dashboardId"`
+	Title       string `json:"title"`
+	Slug        string `json:"slug"`
+	Size        int    `json:"size"`
+}
+
+type GetDashboardsByOrgAndMaxSizeQuery struct {
+	OrgId    int64
+	MaxBytes int
+	Result   []*DashboardWithSize
+}
+
+type GetDashboardsByOrgExcludingPluginDashboardsQuery struct {
+	OrgId  int64
+	Page   int
+	Limit  int
+	Result []*Dashboard
+}
+
+type DashboardWithPendingAlerts struct {
+	DashboardId  int64  `json:"dashboardId"`
+	Title        string `json:"title"`
+	Slug         string `json:"slug"`
+	PendingCount int    `json:"pendingCount"`
+}
+
+type GetDashboardsByAlertPendingDurationQuery struct {
+	OrgId              int64
+	PendingForMoreThan time.Duration
+	Result             []*DashboardWithPendingAlerts
+}
+
+type GetDashboardsByOrgAndUpdatedBeforeDateQuery struct {
+	OrgId         int64
+	UpdatedBefore time.Time
+	Page          int
+	Limit         int
+	Result        []*Dashboard
+}
+
+type DashboardWithAlertTransitions struct {
+	DashboardId      int64     `json:"dashboardId"`
+	Title            string    `json:"title"`
+	Slug             string    `json:"slug"`
+	TransitionCount  int       `json:"transitionCount"`
+	LastTransitionAt time.Time `json:"lastTransitionAt"`
+}
+
+type GetDashboardsByOrgWithRecentAlertTransitionsQuery struct {
+	OrgId  int64
+	Since  time.Time
+	Result []*DashboardWithAlertTransitions
+}
+
 type GetDashboardSlugByIdQuery struct {
 	Id     int64
 	Result string
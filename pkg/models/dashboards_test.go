@@ -2,6 +2,7 @@ package models
 
 import (
 	"testing"
+	"time"
 
 	"github.com/grafana/grafana/pkg/components/simplejson"
 	. "github.com/smartystreets/goconvey/convey"
@@ -26,6 +27,80 @@ func TestDashboardModel(t *testing.T) {
 
 			So(len(dash.GetTags()), ShouldEqual, 0)
 		})
+
+		Convey("With panels in rows and at the top level", func() {
+			json.Set("rows", []interface{}{
+				map[string]interface{}{"panels": []interface{}{
+					map[string]interface{}{"id": 1, "datasource": nil},
+					map[string]interface{}{"id": 2, "datasource": "graphite"},
+				}},
+			})
+			json.Set("panels", []interface{}{
+				map[string]interface{}{"id": 3, "type": "row", "panels": []interface{}{
+					map[string]interface{}{"id": 4, "datasource": "-- Mixed --", "targets": []interface{}{
+						map[string]interface{}{"datasource": "prometheus"},
+						map[string]interface{}{"datasource": "graphite"},
+					}},
+				}},
+			})
+			dash := NewDashboardFromJson(json)
+
+			So(len(dash.GetPanels()), ShouldEqual, 4)
+			So(dash.GetDatasourceRefs(), ShouldResemble, []string{"", "graphite", "-- Mixed --", "prometheus"})
+		})
+
+		Convey("With panel query timeouts", func() {
+			json.Set("panels", []interface{}{
+				map[string]interface{}{"id": 1, "timeout": "30s"},
+				map[string]interface{}{"id": 2, "options": map[string]interface{}{"timeout": 120}},
+				map[string]interface{}{"id": 3},
+			})
+			dash := NewDashboardFromJson(json)
+
+			So(dash.GetMaxQueryTimeout(), ShouldEqual, 2*time.Minute)
+		})
+
+		Convey("With relative time range", func() {
+			json.Set("time", map[string]interface{}{"from": "now-1h", "to": "now"})
+			dash := NewDashboardFromJson(json)
+
+			So(dash.HasAbsoluteTimeRange(), ShouldBeFalse)
+		})
+
+		Convey("With epoch time range", func() {
+			json.Set("time", map[string]interface{}{"from": "1609459200000", "to": 1609462800000})
+			dash := NewDashboardFromJson(json)
+
+			So(dash.HasAbsoluteTimeRange(), ShouldBeTrue)
+		})
+
+		Convey("With ISO time range", func() {
+			json.Set("time", map[string]interface{}{"from": "2021-01-01T00:00:00.000Z", "to": "2021-01-02T00:00:00.000Z"})
+			dash := NewDashboardFromJson(json)
+
+			So(dash.HasAbsoluteTimeRange(), ShouldBeTrue)
+		})
+
+		Convey("With refresh intervals", func() {
+			intervals := map[interface{}]time.Duration{
+				"5s":   5 * time.Second,
+				"1m":   time.Minute,
+				"2h":   2 * time.Hour,
+				"1d":   24 * time.Hour,
+				"":     0,
+				false:  0,
+				"soon": 0,
+			}
+
+			for refresh, expected := range intervals {
+				json.Set("refresh", refresh)
+				dash := NewDashboardFromJson(json)
+
+				interval, enabled := dash.GetRefreshInterval()
+				So(interval, ShouldEqual, expected)
+				So(enabled, ShouldEqual, expected > 0)
+			}
+		})
 	})
 
 }
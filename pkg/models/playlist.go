@@ -106,3 +106,14 @@ type GetPlaylistItemsByIdQuery struct {
 	PlaylistId int64
 	Result     *[]PlaylistItem
 }
+
+type GetDashboardsByPlaylistIdQuery struct {
+	PlaylistId int64
+	OrgId      int64
+	Result     []*Dashboard
+}
+
+type GetDashboardsInAnyPlaylistQuery struct {
+	OrgId  int64
+	Result []*Dashboard
+}
@@ -2,27 +2,114 @@ package conditions
 
 import (
 	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/grafana/grafana/pkg/components/null"
 	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/log"
 	"github.com/grafana/grafana/pkg/services/alerting"
+	"github.com/grafana/grafana/pkg/tsdb"
 )
 
 var (
-	defaultTypes []string = []string{"gt", "lt"}
+	defaultTypes []string = []string{"gt", "lt", "eq", "ne"}
 	rangedTypes  []string = []string{"within_range", "outside_range"}
+
+	// multi threshold types and the comparison they apply to each threshold
+	multiThresholdTypes map[string]string = map[string]string{"gt_multi": "gt", "lt_multi": "lt"}
+
+	evaluatorLog log.Logger = log.New("alerting.evaluator")
+)
+
+type EvaluatorFactory func(model *simplejson.Json) (AlertEvaluator, error)
+
+var (
+	evaluatorFactories     map[string]EvaluatorFactory = make(map[string]EvaluatorFactory)
+	evaluatorFactoriesLock sync.RWMutex
 )
 
+// RegisterEvaluator makes an evaluator type available to alert conditions,
+// registering an existing type replaces it
+func RegisterEvaluator(typeName string, factory EvaluatorFactory) {
+	evaluatorFactoriesLock.Lock()
+	defer evaluatorFactoriesLock.Unlock()
+
+	evaluatorFactories[typeName] = factory
+}
+
+func init() {
+	for _, typ := range defaultTypes {
+		typ := typ
+		RegisterEvaluator(typ, func(model *simplejson.Json) (AlertEvaluator, error) {
+			return newThresholdEvaluator(typ, model)
+		})
+	}
+
+	for typ, comparison := range multiThresholdTypes {
+		comparison := comparison
+		RegisterEvaluator(typ, func(model *simplejson.Json) (AlertEvaluator, error) {
+			return newMultiThresholdEvaluator(comparison, model)
+		})
+	}
+
+	for _, typ := range rangedTypes {
+		typ := typ
+		RegisterEvaluator(typ, func(model *simplejson.Json) (AlertEvaluator, error) {
+			return newRangedEvaluator(typ, model)
+		})
+	}
+
+	RegisterEvaluator("no_value", func(model *simplejson.Json) (AlertEvaluator, error) {
+		return &NoValueEvaluator{}, nil
+	})
+
+	RegisterEvaluator("has_value", func(model *simplejson.Json) (AlertEvaluator, error) {
+		return &HasValueEvaluator{}, nil
+	})
+
+	RegisterEvaluator("percent_diff", func(model *simplejson.Json) (AlertEvaluator, error) {
+		return newPercentDiffEvaluator(model)
+	})
+}
+
 type AlertEvaluator interface {
 	Eval(reducedValue null.Float) bool
 }
 
+// SeriesAlertEvaluator is implemented by evaluators that need the raw
+// series points in addition to the reduced value
+type SeriesAlertEvaluator interface {
+	EvalSeries(series *tsdb.TimeSeries, reducedValue null.Float) bool
+}
+
 type NoValueEvaluator struct{}
 
 func (e *NoValueEvaluator) Eval(reducedValue null.Float) bool {
 	return reducedValue.Valid == false
 }
 
+func (e *NoValueEvaluator) String() string {
+	return "no value"
+}
+
+type HasValueEvaluator struct{}
+
+func (e *HasValueEvaluator) Eval(reducedValue null.Float) bool {
+	return reducedValue.Valid
+}
+
+func (e *HasValueEvaluator) String() string {
+	return "has value"
+}
+
+// equalityTolerance is the max difference for eq and ne to consider
+// two values equal, to cover float rounding
+const equalityTolerance = 1e-9
+
 type ThresholdEvaluator struct {
 	Type      string
 	Threshold float64
@@ -34,14 +121,22 @@ func newThresholdEvaluator(typ string, model *simplejson.Json) (*ThresholdEvalua
 		return nil, alerting.ValidationError{Reason: "Evaluator missing threshold parameter"}
 	}
 
-	firstParam, ok := params[0].(json.Number)
-	if !ok {
-		return nil, alerting.ValidationError{Reason: "Evaluator has invalid parameter"}
+	// extra params used to be ignored silently, several thresholds need
+	// one of the multi threshold types
+	if len(params) > 1 {
+		reason := fmt.Sprintf("Evaluator %s takes a single threshold parameter, got %d", typ, len(params))
+		if _, exists := multiThresholdTypes[typ+"_multi"]; exists {
+			reason += fmt.Sprintf(", use %s_multi for several thresholds", typ)
+		}
+		return nil, alerting.ValidationError{Reason: reason}
+	}
+
+	threshold, err := parseEvaluatorParam(params[0], "parameter")
+	if err != nil {
+		return nil, err
 	}
 
-	defaultEval := &ThresholdEvaluator{Type: typ}
-	defaultEval.Threshold, _ = firstParam.Float64()
-	return defaultEval, nil
+	return &ThresholdEvaluator{Type: typ, Threshold: threshold}, nil
 }
 
 func (e *ThresholdEvaluator) Eval(reducedValue null.Float) bool {
@@ -54,15 +149,110 @@ func (e *ThresholdEvaluator) Eval(reducedValue null.Float) bool {
 		return reducedValue.Float64 > e.Threshold
 	case "lt":
 		return reducedValue.Float64 < e.Threshold
+	case "eq":
+		return math.Abs(reducedValue.Float64-e.Threshold) <= equalityTolerance
+	case "ne":
+		return math.Abs(reducedValue.Float64-e.Threshold) > equalityTolerance
 	}
 
 	return false
 }
 
+func (e *ThresholdEvaluator) String() string {
+	return fmt.Sprintf("value %s %s", e.Type, formatEvaluatorParam(e.Threshold))
+}
+
+// MultiThresholdEvaluator checks the reduced value against several
+// thresholds, e.g. gt 90 and gt 95 for different severities. Rules opt in
+// with the gt_multi and lt_multi types, Type is the comparison, gt or lt.
+type MultiThresholdEvaluator struct {
+	Type       string
+	Thresholds []float64
+}
+
+func newMultiThresholdEvaluator(typ string, model *simplejson.Json) (*MultiThresholdEvaluator, error) {
+	params := model.Get("params").MustArray()
+	if len(params) == 0 {
+		return nil, alerting.ValidationError{Reason: "Evaluator missing threshold parameter"}
+	}
+
+	multiEval := &MultiThresholdEvaluator{Type: typ}
+	for i, param := range params {
+		threshold, err := parseEvaluatorParam(param, fmt.Sprintf("parameter %d", i+1))
+		if err != nil {
+			return nil, err
+		}
+		multiEval.Thresholds = append(multiEval.Thresholds, threshold)
+	}
+
+	return multiEval, nil
+}
+
+func (e *MultiThresholdEvaluator) Eval(reducedValue null.Float) bool {
+	_, crossed := e.Crossed(reducedValue)
+	return crossed
+}
+
+// Crossed returns the most severe threshold crossed by the reduced value,
+// the highest one for gt and the lowest one for lt
+func (e *MultiThresholdEvaluator) Crossed(reducedValue null.Float) (float64, bool) {
+	result := null.FloatFromPtr(nil)
+
+	if reducedValue.Valid == false {
+		return result.Float64, result.Valid
+	}
+
+	for _, threshold := range e.Thresholds {
+		switch e.Type {
+		case "gt":
+			if reducedValue.Float64 <= threshold {
+				continue
+			}
+		case "lt":
+			if reducedValue.Float64 >= threshold {
+				continue
+			}
+		default:
+			continue
+		}
+
+		if e.MoreSevere(threshold, result) {
+			result = null.FloatFrom(threshold)
+		}
+	}
+
+	return result.Float64, result.Valid
+}
+
+// MoreSevere reports whether threshold is more severe than other, any
+// threshold is more severe than a null one
+func (e *MultiThresholdEvaluator) MoreSevere(threshold float64, other null.Float) bool {
+	if other.Valid == false {
+		return true
+	}
+
+	if e.Type == "lt" {
+		return threshold < other.Float64
+	}
+
+	return threshold > other.Float64
+}
+
+func (e *MultiThresholdEvaluator) String() string {
+	thresholds := make([]string, 0)
+	for _, threshold := range e.Thresholds {
+		thresholds = append(thresholds, formatEvaluatorParam(threshold))
+	}
+
+	return fmt.Sprintf("value %s %s", e.Type, strings.Join(thresholds, ", "))
+}
+
 type RangedEvaluator struct {
 	Type  string
 	Lower float64
 	Upper float64
+	// Inclusive makes values on the boundaries part of the range
+	Inclusive bool
 }
 
 func newRangedEvaluator(typ string, model *simplejson.Json) (*RangedEvaluator, error) {
@@ -71,19 +261,29 @@ func newRangedEvaluator(typ string, model *simplejson.Json) (*RangedEvaluator, e
 		return nil, alerting.ValidationError{Reason: "Evaluator missing threshold parameter"}
 	}
 
-	firstParam, ok := params[0].(json.Number)
-	if !ok {
-		return nil, alerting.ValidationError{Reason: "Evaluator has invalid parameter"}
+	if len(params) < 2 {
+		return nil, alerting.ValidationError{Reason: "Evaluator missing second threshold parameter"}
 	}
 
-	secondParam, ok := params[1].(json.Number)
-	if !ok {
-		return nil, alerting.ValidationError{Reason: "Evaluator has invalid second parameter"}
+	lower, err := parseEvaluatorParam(params[0], "parameter")
+	if err != nil {
+		return nil, err
+	}
+
+	upper, err := parseEvaluatorParam(params[1], "second parameter")
+	if err != nil {
+		return nil, err
+	}
+
+	rangedEval := &RangedEvaluator{Type: typ, Lower: lower, Upper: upper, Inclusive: model.Get("inclusive").MustBool(false)}
+
+	// params are often entered the wrong way around, always keep Lower as the
+	// smaller value so the range means the same either way
+	if rangedEval.Lower > rangedEval.Upper {
+		evaluatorLog.Info("Swapping ranged evaluator params, lower is greater than upper", "type", typ, "lower", rangedEval.Lower, "upper", rangedEval.Upper)
+		rangedEval.Lower, rangedEval.Upper = rangedEval.Upper, rangedEval.Lower
 	}
 
-	rangedEval := &RangedEvaluator{Type: typ}
-	rangedEval.Lower, _ = firstParam.Float64()
-	rangedEval.Upper, _ = secondParam.Float64()
 	return rangedEval, nil
 }
 
@@ -94,6 +294,17 @@ func (e *RangedEvaluator) Eval(reducedValue null.Float) bool {
 
 	floatValue := reducedValue.Float64
 
+	if e.Inclusive {
+		switch e.Type {
+		case "within_range":
+			return e.Lower <= floatValue && floatValue <= e.Upper
+		case "outside_range":
+			return floatValue <= e.Lower || floatValue >= e.Upper
+		}
+
+		return false
+	}
+
 	switch e.Type {
 	case "within_range":
 		return (e.Lower < floatValue && e.Upper > floatValue) || (e.Upper < floatValue && e.Lower > floatValue)
@@ -104,25 +315,108 @@ func (e *RangedEvaluator) Eval(reducedValue null.Float) bool {
 	return false
 }
 
+func (e *RangedEvaluator) String() string {
+	description := "within"
+	if e.Type == "outside_range" {
+		description = "outside"
+	}
+
+	description = fmt.Sprintf("value %s %s to %s", description, formatEvaluatorParam(e.Lower), formatEvaluatorParam(e.Upper))
+	if e.Inclusive {
+		description += " inclusive"
+	}
+
+	return description
+}
+
+// PercentDiffEvaluator compares the reduced value with the first point of
+// the series and matches when it changed by more than Threshold percent
+type PercentDiffEvaluator struct {
+	Threshold float64
+}
+
+func newPercentDiffEvaluator(model *simplejson.Json) (*PercentDiffEvaluator, error) {
+	params := model.Get("params").MustArray()
+	if len(params) == 0 {
+		return nil, alerting.ValidationError{Reason: "Evaluator missing threshold parameter"}
+	}
+
+	threshold, err := parseEvaluatorParam(params[0], "parameter")
+	if err != nil {
+		return nil, err
+	}
+
+	return &PercentDiffEvaluator{Threshold: threshold}, nil
+}
+
+// Eval cannot compute a difference without the series
+func (e *PercentDiffEvaluator) Eval(reducedValue null.Float) bool {
+	return false
+}
+
+func (e *PercentDiffEvaluator) EvalSeries(series *tsdb.TimeSeries, reducedValue null.Float) bool {
+	if reducedValue.Valid == false || series == nil {
+		return false
+	}
+
+	for _, point := range series.Points {
+		baseline := point[0]
+		if baseline.Valid == false {
+			continue
+		}
+
+		// the change relative to zero is undefined
+		if baseline.Float64 == 0 {
+			return false
+		}
+
+		diff := math.Abs((reducedValue.Float64 - baseline.Float64) / baseline.Float64 * 100)
+		return diff > e.Threshold
+	}
+
+	return false
+}
+
+func (e *PercentDiffEvaluator) String() string {
+	return fmt.Sprintf("value changed by more than %s%%", formatEvaluatorParam(e.Threshold))
+}
+
 func NewAlertEvaluator(model *simplejson.Json) (AlertEvaluator, error) {
 	typ := model.Get("type").MustString()
 	if typ == "" {
 		return nil, alerting.ValidationError{Reason: "Evaluator missing type property"}
 	}
 
-	if inSlice(typ, defaultTypes) {
-		return newThresholdEvaluator(typ, model)
-	}
+	evaluatorFactoriesLock.RLock()
+	factory, exists := evaluatorFactories[typ]
+	evaluatorFactoriesLock.RUnlock()
 
-	if inSlice(typ, rangedTypes) {
-		return newRangedEvaluator(typ, model)
+	if !exists {
+		return nil, alerting.ValidationError{Reason: "Evaluator invalid evaluator type: " + typ}
 	}
 
-	if typ == "no_value" {
-		return &NoValueEvaluator{}, nil
+	return factory(model)
+}
+
+// parseEvaluatorParam reads a threshold param, numbers saved as strings by
+// hand edited dashboards are accepted as long as they parse
+func parseEvaluatorParam(param interface{}, name string) (float64, error) {
+	switch value := param.(type) {
+	case json.Number:
+		if f, err := value.Float64(); err == nil {
+			return f, nil
+		}
+	case string:
+		if f, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+			return f, nil
+		}
 	}
 
-	return nil, alerting.ValidationError{Reason: "Evaluator invalid evaluator type: " + typ}
+	return 0, alerting.ValidationError{Reason: fmt.Sprintf("Evaluator has invalid %s %#v (%T), expected a number", name, param, param)}
+}
+
+func formatEvaluatorParam(param float64) string {
+	return strconv.FormatFloat(param, 'f', -1, 64)
 }
 
 func inSlice(a string, list []string) bool {
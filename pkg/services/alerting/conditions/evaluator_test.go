@@ -1,12 +1,15 @@
 package conditions
 
 import (
+	"fmt"
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
 
 	"github.com/grafana/grafana/pkg/components/null"
 	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/services/alerting"
+	"github.com/grafana/grafana/pkg/tsdb"
 )
 
 func evalutorScenario(json string, reducedValue float64, datapoints ...float64) bool {
@@ -16,6 +19,15 @@ func evalutorScenario(json string, reducedValue float64, datapoints ...float64)
 	evaluator, err := NewAlertEvaluator(jsonModel)
 	So(err, ShouldBeNil)
 
+	if seriesEvaluator, ok := evaluator.(SeriesAlertEvaluator); ok {
+		points := make(tsdb.TimeSeriesPoints, 0)
+		for i, value := range datapoints {
+			points = append(points, tsdb.NewTimePoint(null.FloatFrom(value), float64(i)))
+		}
+
+		return seriesEvaluator.EvalSeries(tsdb.NewTimeSeries("test", points), null.FloatFrom(reducedValue))
+	}
+
 	return evaluator.Eval(null.FloatFrom(reducedValue))
 }
 
@@ -30,6 +42,54 @@ func TestEvalutors(t *testing.T) {
 		So(evalutorScenario(`{"type": "lt", "params": [3] }`, 1), ShouldBeTrue)
 	})
 
+	Convey("equal", t, func() {
+		a, b := 0.1, 0.2
+
+		So(evalutorScenario(`{"type": "eq", "params": [2] }`, 2), ShouldBeTrue)
+		So(evalutorScenario(`{"type": "eq", "params": [2] }`, 1), ShouldBeFalse)
+		So(evalutorScenario(`{"type": "eq", "params": [0.3] }`, a+b), ShouldBeTrue)
+	})
+
+	Convey("not equal", t, func() {
+		a, b := 0.1, 0.2
+
+		So(evalutorScenario(`{"type": "ne", "params": [2] }`, 1), ShouldBeTrue)
+		So(evalutorScenario(`{"type": "ne", "params": [2] }`, 2), ShouldBeFalse)
+		So(evalutorScenario(`{"type": "ne", "params": [0.3] }`, a+b), ShouldBeFalse)
+	})
+
+	Convey("multiple thresholds", t, func() {
+		So(evalutorScenario(`{"type": "gt_multi", "params": [90, 95] }`, 91), ShouldBeTrue)
+		So(evalutorScenario(`{"type": "gt_multi", "params": [90, 95] }`, 89), ShouldBeFalse)
+		So(evalutorScenario(`{"type": "lt_multi", "params": [10, 5] }`, 7), ShouldBeTrue)
+		So(evalutorScenario(`{"type": "lt_multi", "params": [10, 5] }`, 11), ShouldBeFalse)
+
+		crossedScenario := func(json string, reducedValue float64) (float64, bool) {
+			jsonModel, err := simplejson.NewJson([]byte(json))
+			So(err, ShouldBeNil)
+
+			evaluator, err := NewAlertEvaluator(jsonModel)
+			So(err, ShouldBeNil)
+
+			return evaluator.(*MultiThresholdEvaluator).Crossed(null.FloatFrom(reducedValue))
+		}
+
+		Convey("should return the most severe crossed threshold", func() {
+			threshold, crossed := crossedScenario(`{"type": "gt_multi", "params": [90, 95] }`, 91)
+			So(crossed, ShouldBeTrue)
+			So(threshold, ShouldEqual, 90)
+
+			threshold, crossed = crossedScenario(`{"type": "gt_multi", "params": [90, 95] }`, 99)
+			So(crossed, ShouldBeTrue)
+			So(threshold, ShouldEqual, 95)
+
+			threshold, crossed = crossedScenario(`{"type": "lt_multi", "params": [10, 5] }`, 1)
+			So(crossed, ShouldBeTrue)
+			So(threshold, ShouldEqual, 5)
+		})
+
+	})
+
 	Convey("within_range", t, func() {
 		So(evalutorScenario(`{"type": "within_range", "params": [1, 100] }`, 3), ShouldBeTrue)
 		So(evalutorScenario(`{"type": "within_range", "params": [1, 100] }`, 300), ShouldBeFalse)
@@ -44,6 +104,124 @@ func TestEvalutors(t *testing.T) {
 		So(evalutorScenario(`{"type": "outside_range", "params": [100, 1] }`, 50), ShouldBeFalse)
 	})
 
+	Convey("percent_diff", t, func() {
+		So(evalutorScenario(`{"type": "percent_diff", "params": [50] }`, 200, 100, 150, 200), ShouldBeTrue)
+		So(evalutorScenario(`{"type": "percent_diff", "params": [50] }`, 40, 100, 70, 40), ShouldBeTrue)
+		So(evalutorScenario(`{"type": "percent_diff", "params": [50] }`, 120, 100, 110, 120), ShouldBeFalse)
+		So(evalutorScenario(`{"type": "percent_diff", "params": [50] }`, 150, 100, 150), ShouldBeFalse)
+
+		Convey("should be false when the baseline is zero", func() {
+			So(evalutorScenario(`{"type": "percent_diff", "params": [50] }`, 100, 0, 100), ShouldBeFalse)
+		})
+
+		Convey("should be false without points", func() {
+			So(evalutorScenario(`{"type": "percent_diff", "params": [50] }`, 100), ShouldBeFalse)
+		})
+
+		Convey("should require a threshold", func() {
+			jsonModel, err := simplejson.NewJson([]byte(`{"type": "percent_diff", "params": [] }`))
+			So(err, ShouldBeNil)
+
+			_, err = NewAlertEvaluator(jsonModel)
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("params", t, func() {
+		Convey("should parse numeric strings", func() {
+			So(evalutorScenario(`{"type": "gt", "params": ["90"] }`, 91), ShouldBeTrue)
+			So(evalutorScenario(`{"type": "within_range", "params": [" 10 ", "20.5"] }`, 15), ShouldBeTrue)
+		})
+
+		Convey("should describe invalid params", func() {
+			jsonModel, err := simplejson.NewJson([]byte(`{"type": "gt", "params": ["ninety"] }`))
+			So(err, ShouldBeNil)
+
+			_, err = NewAlertEvaluator(jsonModel)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, `"ninety" (string)`)
+		})
+
+		Convey("should describe invalid second params", func() {
+			jsonModel, err := simplejson.NewJson([]byte(`{"type": "outside_range", "params": [10, true] }`))
+			So(err, ShouldBeNil)
+
+			_, err = NewAlertEvaluator(jsonModel)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "second parameter true (bool)")
+		})
+
+		Convey("should reject several thresholds for single threshold types", func() {
+			jsonModel, err := simplejson.NewJson([]byte(`{"type": "gt", "params": [90, 95] }`))
+			So(err, ShouldBeNil)
+
+			_, err = NewAlertEvaluator(jsonModel)
+			_, isValidationError := err.(alerting.ValidationError)
+			So(isValidationError, ShouldBeTrue)
+			So(err.Error(), ShouldContainSubstring, "use gt_multi")
+
+			jsonModel, err = simplejson.NewJson([]byte(`{"type": "eq", "params": [1, 2] }`))
+			So(err, ShouldBeNil)
+
+			_, err = NewAlertEvaluator(jsonModel)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("should fail when the second param is missing", func() {
+			jsonModel, err := simplejson.NewJson([]byte(`{"type": "within_range", "params": [10] }`))
+			So(err, ShouldBeNil)
+
+			_, err = NewAlertEvaluator(jsonModel)
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("ranged params", t, func() {
+		Convey("should swap lower and upper when given the wrong way around", func() {
+			jsonModel, err := simplejson.NewJson([]byte(`{"type": "within_range", "params": [100, 1] }`))
+			So(err, ShouldBeNil)
+
+			evaluator, err := NewAlertEvaluator(jsonModel)
+			So(err, ShouldBeNil)
+
+			rangedEval := evaluator.(*RangedEvaluator)
+			So(rangedEval.Lower, ShouldEqual, 1)
+			So(rangedEval.Upper, ShouldEqual, 100)
+		})
+
+		Convey("should keep lower and upper in order", func() {
+			jsonModel, err := simplejson.NewJson([]byte(`{"type": "outside_range", "params": [1, 100] }`))
+			So(err, ShouldBeNil)
+
+			evaluator, err := NewAlertEvaluator(jsonModel)
+			So(err, ShouldBeNil)
+
+			rangedEval := evaluator.(*RangedEvaluator)
+			So(rangedEval.Lower, ShouldEqual, 1)
+			So(rangedEval.Upper, ShouldEqual, 100)
+		})
+	})
+
+	Convey("range boundaries", t, func() {
+		Convey("should be exclusive by default", func() {
+			So(evalutorScenario(`{"type": "within_range", "params": [1, 100] }`, 1), ShouldBeFalse)
+			So(evalutorScenario(`{"type": "within_range", "params": [1, 100] }`, 100), ShouldBeFalse)
+			So(evalutorScenario(`{"type": "outside_range", "params": [1, 100] }`, 1), ShouldBeFalse)
+			So(evalutorScenario(`{"type": "outside_range", "params": [1, 100] }`, 100), ShouldBeFalse)
+		})
+
+		Convey("should include the boundaries when inclusive", func() {
+			So(evalutorScenario(`{"type": "within_range", "params": [1, 100], "inclusive": true }`, 1), ShouldBeTrue)
+			So(evalutorScenario(`{"type": "within_range", "params": [1, 100], "inclusive": true }`, 100), ShouldBeTrue)
+			So(evalutorScenario(`{"type": "within_range", "params": [100, 1], "inclusive": true }`, 100), ShouldBeTrue)
+			So(evalutorScenario(`{"type": "within_range", "params": [1, 100], "inclusive": true }`, 101), ShouldBeFalse)
+			So(evalutorScenario(`{"type": "outside_range", "params": [1, 100], "inclusive": true }`, 1), ShouldBeTrue)
+			So(evalutorScenario(`{"type": "outside_range", "params": [1, 100], "inclusive": true }`, 100), ShouldBeTrue)
+			So(evalutorScenario(`{"type": "outside_range", "params": [100, 1], "inclusive": true }`, 1), ShouldBeTrue)
+			So(evalutorScenario(`{"type": "outside_range", "params": [1, 100], "inclusive": true }`, 50), ShouldBeFalse)
+		})
+	})
+
 	Convey("no_value", t, func() {
 		Convey("should be false if serie have values", func() {
 			So(evalutorScenario(`{"type": "no_value", "params": [] }`, 50), ShouldBeFalse)
@@ -60,4 +238,93 @@ func TestEvalutors(t *testing.T) {
 
 		})
 	})
+
+	Convey("has_value", t, func() {
+		Convey("should be true if serie have values", func() {
+			So(evalutorScenario(`{"type": "has_value", "params": [] }`, 50), ShouldBeTrue)
+		})
+
+		Convey("should be false when the serie have no value", func() {
+			jsonModel, err := simplejson.NewJson([]byte(`{"type": "has_value", "params": [] }`))
+			So(err, ShouldBeNil)
+
+			evaluator, err := NewAlertEvaluator(jsonModel)
+			So(err, ShouldBeNil)
+
+			So(evaluator.Eval(null.FloatFromPtr(nil)), ShouldBeFalse)
+		})
+	})
+}
+
+type fakeEvaluator struct {
+	model *simplejson.Json
+}
+
+func (e *fakeEvaluator) Eval(reducedValue null.Float) bool {
+	return reducedValue.Valid && reducedValue.Float64 == e.model.Get("match").MustFloat64()
+}
+
+func unregisterEvaluator(typeName string) {
+	evaluatorFactoriesLock.Lock()
+	defer evaluatorFactoriesLock.Unlock()
+
+	delete(evaluatorFactories, typeName)
+}
+
+func TestEvaluatorRegistry(t *testing.T) {
+	Convey("Evaluator registry", t, func() {
+		Convey("should create registered custom evaluators", func() {
+			RegisterEvaluator("fake_match", func(model *simplejson.Json) (AlertEvaluator, error) {
+				return &fakeEvaluator{model: model}, nil
+			})
+			defer unregisterEvaluator("fake_match")
+
+			So(evalutorScenario(`{"type": "fake_match", "match": 42 }`, 42), ShouldBeTrue)
+			So(evalutorScenario(`{"type": "fake_match", "match": 42 }`, 41), ShouldBeFalse)
+		})
+
+		Convey("should not keep custom evaluators registered by other tests", func() {
+			jsonModel, err := simplejson.NewJson([]byte(`{"type": "fake_match", "match": 42 }`))
+			So(err, ShouldBeNil)
+
+			_, err = NewAlertEvaluator(jsonModel)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("should fail for unknown types", func() {
+			jsonModel, err := simplejson.NewJson([]byte(`{"type": "unknown", "params": [] }`))
+			So(err, ShouldBeNil)
+
+			_, err = NewAlertEvaluator(jsonModel)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "invalid evaluator type: unknown")
+		})
+	})
+}
+
+func TestEvaluatorDescriptions(t *testing.T) {
+	Convey("Evaluators should describe their threshold", t, func() {
+		descriptions := map[string]string{
+			`{"type": "gt", "params": [90] }`:                                  "value gt 90",
+			`{"type": "lt", "params": [0.5] }`:                                 "value lt 0.5",
+			`{"type": "eq", "params": [2] }`:                                   "value eq 2",
+			`{"type": "ne", "params": [2] }`:                                   "value ne 2",
+			`{"type": "within_range", "params": [10, 20] }`:                    "value within 10 to 20",
+			`{"type": "outside_range", "params": [20, 10] }`:                   "value outside 10 to 20",
+			`{"type": "within_range", "params": [10, 20], "inclusive": true }`: "value within 10 to 20 inclusive",
+			`{"type": "percent_diff", "params": [25] }`:                        "value changed by more than 25%",
+			`{"type": "no_value", "params": [] }`:                              "no value",
+			`{"type": "has_value", "params": [] }`:                             "has value",
+		}
+
+		for model, description := range descriptions {
+			jsonModel, err := simplejson.NewJson([]byte(model))
+			So(err, ShouldBeNil)
+
+			evaluator, err := NewAlertEvaluator(jsonModel)
+			So(err, ShouldBeNil)
+
+			So(evaluator.(fmt.Stringer).String(), ShouldEqual, description)
+		}
+	})
 }
@@ -49,9 +49,18 @@ func (c *QueryCondition) Eval(context *alerting.EvalContext) (*alerting.Conditio
 	evalMatchCount := 0
 	var matches []*alerting.EvalMatch
 
+	multiEval, isMultiThreshold := c.Evaluator.(*MultiThresholdEvaluator)
+	crossedThreshold := null.FloatFromPtr(nil)
+
 	for _, series := range seriesList {
 		reducedValue := c.Reducer.Reduce(series)
-		evalMatch := c.Evaluator.Eval(reducedValue)
+
+		var evalMatch bool
+		if seriesEvaluator, ok := c.Evaluator.(SeriesAlertEvaluator); ok {
+			evalMatch = seriesEvaluator.EvalSeries(series, reducedValue)
+		} else {
+			evalMatch = c.Evaluator.Eval(reducedValue)
+		}
 
 		if reducedValue.Valid == false {
 			emptySerieCount++
@@ -66,11 +75,22 @@ func (c *QueryCondition) Eval(context *alerting.EvalContext) (*alerting.Conditio
 		if evalMatch {
 			evalMatchCount++
 
-			matches = append(matches, &alerting.EvalMatch{
+			match := &alerting.EvalMatch{
 				Metric: series.Name,
 				Value:  reducedValue,
 				Tags:   series.Tags,
-			})
+			}
+
+			if isMultiThreshold {
+				if threshold, crossed := multiEval.Crossed(reducedValue); crossed {
+					match.Threshold = &threshold
+					if multiEval.MoreSevere(threshold, crossedThreshold) {
+						crossedThreshold = null.FloatFrom(threshold)
+					}
+				}
+			}
+
+			matches = append(matches, match)
 		}
 	}
 
@@ -96,6 +116,7 @@ func (c *QueryCondition) Eval(context *alerting.EvalContext) (*alerting.Conditio
 		NoDataFound: emptySerieCount == len(seriesList),
 		Operator:    c.Operator,
 		EvalMatches: matches,
+		Threshold:   crossedThreshold.Ptr(),
 	}, nil
 }
 
@@ -17,6 +17,37 @@ func TestQueryCondition(t *testing.T) {
 
 	Convey("when evaluating query condition", t, func() {
 
+		queryConditionScenario("Given avg() and > 90, 95 with gt_multi", func(ctx *queryConditionTestContext) {
+
+			ctx.reducer = `{"type": "avg"}`
+			ctx.evaluator = `{"type": "gt_multi", "params": [90, 95]}`
+
+			Convey("should pass the crossed thresholds to the result", func() {
+				ctx.series = tsdb.TimeSeriesSlice{
+					tsdb.NewTimeSeries("test1", tsdb.NewTimeSeriesPointsFromArgs(92, 0)),
+					tsdb.NewTimeSeries("test2", tsdb.NewTimeSeriesPointsFromArgs(99, 0)),
+					tsdb.NewTimeSeries("test3", tsdb.NewTimeSeriesPointsFromArgs(50, 0)),
+				}
+				cr, err := ctx.exec()
+
+				So(err, ShouldBeNil)
+				So(cr.Firing, ShouldBeTrue)
+				So(len(cr.EvalMatches), ShouldEqual, 2)
+				So(*cr.EvalMatches[0].Threshold, ShouldEqual, 90)
+				So(*cr.EvalMatches[1].Threshold, ShouldEqual, 95)
+				So(*cr.Threshold, ShouldEqual, 95)
+			})
+
+			Convey("should not set a threshold when none is crossed", func() {
+				ctx.series = tsdb.TimeSeriesSlice{tsdb.NewTimeSeries("test1", tsdb.NewTimeSeriesPointsFromArgs(50, 0))}
+				cr, err := ctx.exec()
+
+				So(err, ShouldBeNil)
+				So(cr.Firing, ShouldBeFalse)
+				So(cr.Threshold, ShouldBeNil)
+			})
+		})
+
 		queryConditionScenario("Given avg() and > 100", func(ctx *queryConditionTestContext) {
 
 			ctx.reducer = `{"type": "avg"}`
@@ -38,6 +38,9 @@ type ConditionResult struct {
 	NoDataFound bool
 	Operator    string
 	EvalMatches []*EvalMatch
+	// Threshold is the most severe threshold crossed by any of the series,
+	// only set by conditions with a multi threshold evaluator
+	Threshold *float64
 }
 
 type Condition interface {
@@ -19,6 +19,8 @@ type EvalMatch struct {
 	Value  null.Float        `json:"value"`
 	Metric string            `json:"metric"`
 	Tags   map[string]string `json:"tags"`
+	// Threshold is the crossed threshold of multi threshold evaluators
+	Threshold *float64 `json:"threshold,omitempty"`
 }
 
 type Level struct {
@@ -3,6 +3,7 @@ package alerting
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"golang.org/x/sync/errgroup"
 
@@ -42,6 +43,11 @@ func newNotificationService() *notificationService {
 }
 
 func (n *notificationService) Send(context *EvalContext) error {
+	if context.Rule.IsSilenced(time.Now()) {
+		n.log.Info("Skipping notifications for silenced alert", "ruleId", context.Rule.Id, "silencedUntil", context.Rule.SilencedUntil)
+		return nil
+	}
+
 	notifiers, err := n.getNotifiers(context.Rule.OrgId, context.Rule.Notifications, context)
 	if err != nil {
 		return err
@@ -2,9 +2,11 @@ package alerting
 
 import (
 	"testing"
+	"time"
 
 	"fmt"
 
+	"github.com/grafana/grafana/pkg/bus"
 	"github.com/grafana/grafana/pkg/models"
 	m "github.com/grafana/grafana/pkg/models"
 	. "github.com/smartystreets/goconvey/convey"
@@ -85,5 +87,36 @@ func TestAlertNotificationExtraction(t *testing.T) {
 
 			So(shouldUseNotification(notifier, ctx), ShouldBeFalse)
 		})
+
+		Convey("silenced alerts", func() {
+			notificationsLoaded := false
+			bus.ClearBusHandlers()
+			bus.AddHandler("test", func(query *m.GetAlertNotificationsToSendQuery) error {
+				notificationsLoaded = true
+				return nil
+			})
+
+			Convey("should not send notifications until the silence ends", func() {
+				until := time.Now().Add(time.Hour)
+				ctx := &EvalContext{
+					Firing: true,
+					Rule:   &Rule{State: m.AlertStateAlerting, SilencedUntil: &until},
+				}
+
+				So(newNotificationService().Send(ctx), ShouldBeNil)
+				So(notificationsLoaded, ShouldBeFalse)
+			})
+
+			Convey("should send notifications after the silence ended", func() {
+				until := time.Now().Add(-time.Hour)
+				ctx := &EvalContext{
+					Firing: true,
+					Rule:   &Rule{State: m.AlertStateAlerting, SilencedUntil: &until},
+				}
+
+				So(newNotificationService().Send(ctx), ShouldBeNil)
+				So(notificationsLoaded, ShouldBeTrue)
+			})
+		})
 	})
 }
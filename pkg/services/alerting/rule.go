@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"regexp"
 	"strconv"
+	"time"
 
 	"github.com/grafana/grafana/pkg/components/simplejson"
 
@@ -23,6 +24,12 @@ type Rule struct {
 	State               m.AlertStateType
 	Conditions          []Condition
 	Notifications       []int64
+	SilencedUntil       *time.Time
+}
+
+// IsSilenced returns true while a silence set on the alert hasn't ended
+func (rule *Rule) IsSilenced(now time.Time) bool {
+	return rule.SilencedUntil != nil && rule.SilencedUntil.After(now)
 }
 
 type ValidationError struct {
@@ -98,6 +105,7 @@ func NewRuleFromDBAlert(ruleDef *m.Alert) (*Rule, error) {
 	model.Message = ruleDef.Message
 	model.Frequency = ruleDef.Frequency
 	model.State = ruleDef.State
+	model.SilencedUntil = ruleDef.SilencedUntil
 	model.NoDataState = m.NoDataOption(ruleDef.Settings.Get("noDataState").MustString("no_data"))
 	model.ExecutionErrorState = m.ExecutionErrorOption(ruleDef.Settings.Get("executionErrorState").MustString("alerting"))
 
@@ -1,6 +1,7 @@
 package dashboards
 
 import (
+	"context"
 	"time"
 
 	"github.com/grafana/grafana/pkg/bus"
@@ -9,7 +10,7 @@ import (
 )
 
 type Repository interface {
-	SaveDashboard(*SaveDashboardItem) (*models.Dashboard, error)
+	SaveDashboard(context.Context, *SaveDashboardItem) (*models.Dashboard, error)
 }
 
 var repositoryInstance Repository
@@ -35,7 +36,9 @@ type SaveDashboardItem struct {
 
 type DashboardRepository struct{}
 
-func (dr *DashboardRepository) SaveDashboard(json *SaveDashboardItem) (*models.Dashboard, error) {
+// SaveDashboard validates and saves the dashboard and its alerts, saving the
+// dashboard stops when ctx is cancelled.
+func (dr *DashboardRepository) SaveDashboard(ctx context.Context, json *SaveDashboardItem) (*models.Dashboard, error) {
 	dashboard := json.Dashboard
 
 	if dashboard.Title == "" {
@@ -63,7 +66,7 @@ func (dr *DashboardRepository) SaveDashboard(json *SaveDashboardItem) (*models.D
 		cmd.UpdatedAt = json.UpdatedAt
 	}
 
-	err := bus.Dispatch(&cmd)
+	err := bus.DispatchCtx(ctx, &cmd)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,522 @@
+package dashboards
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+// LatestSchemaVersion is the dashboard schema version the frontend
+// DashboardModel upgrades dashboards to when they are loaded
+const LatestSchemaVersion = 14
+
+// UpgradeSchemas upgrades the json of a batch of dashboards to
+// LatestSchemaVersion and returns the dashboards that were changed
+func UpgradeSchemas(batch []*models.Dashboard) []*models.Dashboard {
+	upgraded := make([]*models.Dashboard, 0)
+
+	for _, dash := range batch {
+		if UpgradeSchema(dash.Data) {
+			upgraded = append(upgraded, dash)
+		}
+	}
+
+	return upgraded
+}
+
+// UpgradeSchema upgrades the dashboard json in place the same way
+// DashboardModel.updateSchema does in the frontend. It returns false when
+// the dashboard is already at, or newer than, LatestSchemaVersion.
+func UpgradeSchema(data *simplejson.Json) bool {
+	dash := data.MustMap()
+	if dash == nil {
+		return false
+	}
+
+	oldVersion := data.Get("schemaVersion").MustInt(0)
+	if oldVersion >= LatestSchemaVersion {
+		return false
+	}
+
+	data.Set("schemaVersion", LatestSchemaVersion)
+
+	templating := ensureList(dash, "templating")
+	panelUpgrades := make([]func(panel map[string]interface{}), 0)
+
+	if oldVersion < 2 {
+		if filter := asMap(asMap(dash["services"])["filter"]); filter != nil {
+			dash["time"] = filter["time"]
+			if list, ok := filter["list"].([]interface{}); ok {
+				templating["list"] = list
+			} else {
+				templating["list"] = []interface{}{}
+			}
+		}
+
+		panelUpgrades = append(panelUpgrades, upgradePanelToV2)
+	}
+
+	if oldVersion < 3 {
+		// ensure panel ids
+		maxId := nextPanelId(dash)
+		panelUpgrades = append(panelUpgrades, func(panel map[string]interface{}) {
+			if !truthy(panel["id"]) {
+				panel["id"] = maxId
+				maxId++
+			}
+		})
+	}
+
+	if oldVersion < 4 {
+		panelUpgrades = append(panelUpgrades, upgradePanelToV4)
+	}
+
+	if oldVersion < 6 {
+		// move pulldowns to the new schema
+		for _, item := range asList(dash["pulldowns"]) {
+			pulldown := asMap(item)
+			if pulldown == nil || pulldown["type"] != "annotations" {
+				continue
+			}
+
+			list, ok := pulldown["annotations"].([]interface{})
+			if !ok {
+				list = []interface{}{}
+			}
+			dash["annotations"] = map[string]interface{}{"list": list}
+			break
+		}
+
+		for _, item := range asList(templating["list"]) {
+			variable := asMap(item)
+			if variable == nil {
+				continue
+			}
+			if _, exists := variable["datasource"]; !exists {
+				variable["datasource"] = nil
+			}
+			if _, exists := variable["type"]; !exists || variable["type"] == "filter" {
+				variable["type"] = "query"
+			}
+			if _, exists := variable["allFormat"]; !exists {
+				variable["allFormat"] = "glob"
+			}
+		}
+	}
+
+	if oldVersion < 7 {
+		if nav := asList(dash["nav"]); len(nav) > 0 {
+			dash["timepicker"] = nav[0]
+		}
+
+		// ensure query refIds
+		panelUpgrades = append(panelUpgrades, func(panel map[string]interface{}) {
+			for _, item := range asList(panel["targets"]) {
+				target := asMap(item)
+				if target != nil && !truthy(target["refId"]) {
+					target["refId"] = nextQueryLetter(panel)
+				}
+			}
+		})
+	}
+
+	if oldVersion < 8 {
+		panelUpgrades = append(panelUpgrades, upgradePanelToV8)
+	}
+
+	if oldVersion < 9 {
+		panelUpgrades = append(panelUpgrades, upgradePanelToV9)
+	}
+
+	if oldVersion < 10 {
+		panelUpgrades = append(panelUpgrades, upgradePanelToV10)
+	}
+
+	if oldVersion < 12 {
+		for _, item := range asList(templating["list"]) {
+			variable := asMap(item)
+			if variable == nil {
+				continue
+			}
+			if truthy(variable["refresh"]) {
+				variable["refresh"] = 1
+			} else {
+				variable["refresh"] = 0
+			}
+			if truthy(variable["hideVariable"]) {
+				variable["hide"] = 2
+			} else if truthy(variable["hideLabel"]) {
+				variable["hide"] = 1
+			}
+		}
+
+		panelUpgrades = append(panelUpgrades, upgradePanelToV12)
+	}
+
+	if oldVersion < 13 {
+		panelUpgrades = append(panelUpgrades, upgradePanelToV13)
+	}
+
+	if oldVersion < 14 {
+		if truthy(dash["sharedCrosshair"]) {
+			dash["graphTooltip"] = 1
+		} else {
+			dash["graphTooltip"] = 0
+		}
+	}
+
+	for _, row := range asList(dash["rows"]) {
+		for _, item := range asList(asMap(row)["panels"]) {
+			if panel := asMap(item); panel != nil {
+				for _, upgrade := range panelUpgrades {
+					upgrade(panel)
+				}
+			}
+		}
+	}
+
+	return true
+}
+
+func upgradePanelToV2(panel map[string]interface{}) {
+	// rename panel type
+	if panel["type"] == "graphite" {
+		panel["type"] = "graph"
+	}
+
+	if panel["type"] != "graph" {
+		return
+	}
+
+	if legend, ok := panel["legend"].(bool); ok {
+		panel["legend"] = map[string]interface{}{"show": legend}
+	}
+
+	if grid := asMap(panel["grid"]); grid != nil {
+		if truthy(grid["min"]) {
+			grid["leftMin"] = grid["min"]
+			delete(grid, "min")
+		}
+
+		if truthy(grid["max"]) {
+			grid["leftMax"] = grid["max"]
+			delete(grid, "max")
+		}
+	}
+
+	if truthy(panel["y_format"]) {
+		setYFormat(panel, 0, panel["y_format"])
+		delete(panel, "y_format")
+	}
+
+	if truthy(panel["y2_format"]) {
+		setYFormat(panel, 1, panel["y2_format"])
+		delete(panel, "y2_format")
+	}
+}
+
+func setYFormat(panel map[string]interface{}, index int, format interface{}) {
+	formats := asList(panel["y_formats"])
+	for len(formats) <= index {
+		formats = append(formats, nil)
+	}
+	formats[index] = format
+	panel["y_formats"] = formats
+}
+
+func upgradePanelToV4(panel map[string]interface{}) {
+	if panel["type"] != "graph" {
+		return
+	}
+
+	// move aliasYAxis to series overrides
+	aliasYAxis := asMap(panel["aliasYAxis"])
+	aliases := make([]string, 0)
+	for alias := range aliasYAxis {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+
+	for _, alias := range aliases {
+		overrides := asList(panel["seriesOverrides"])
+		panel["seriesOverrides"] = append(overrides, map[string]interface{}{"alias": alias, "yaxis": aliasYAxis[alias]})
+	}
+
+	delete(panel, "aliasYAxis")
+}
+
+// upgradePanelToV8 converts the old influxdb query schema
+func upgradePanelToV8(panel map[string]interface{}) {
+	for _, item := range asList(panel["targets"]) {
+		target := asMap(item)
+		if target == nil || !truthy(target["fields"]) || !truthy(target["tags"]) || !truthy(target["groupBy"]) {
+			continue
+		}
+
+		if truthy(target["rawQuery"]) {
+			delete(target, "fields")
+			delete(target, "fill")
+			continue
+		}
+
+		selects := make([]interface{}, 0)
+		for _, f := range asList(target["fields"]) {
+			field := asMap(f)
+			parts := []interface{}{
+				map[string]interface{}{"type": "field", "params": []interface{}{field["name"]}},
+				map[string]interface{}{"type": field["func"], "params": []interface{}{}},
+			}
+			if truthy(field["mathExpr"]) {
+				parts = append(parts, map[string]interface{}{"type": "math", "params": []interface{}{field["mathExpr"]}})
+			}
+			if truthy(field["asExpr"]) {
+				parts = append(parts, map[string]interface{}{"type": "alias", "params": []interface{}{field["asExpr"]}})
+			}
+			selects = append(selects, parts)
+		}
+		target["select"] = selects
+		delete(target, "fields")
+
+		groupBy := asList(target["groupBy"])
+		for _, p := range groupBy {
+			part := asMap(p)
+			if part == nil {
+				continue
+			}
+			if part["type"] == "time" && truthy(part["interval"]) {
+				part["params"] = []interface{}{part["interval"]}
+				delete(part, "interval")
+			}
+			if part["type"] == "tag" && truthy(part["key"]) {
+				part["params"] = []interface{}{part["key"]}
+				delete(part, "key")
+			}
+		}
+
+		if truthy(target["fill"]) {
+			groupBy = append(groupBy, map[string]interface{}{"type": "fill", "params": []interface{}{target["fill"]}})
+			delete(target, "fill")
+		}
+		target["groupBy"] = groupBy
+	}
+}
+
+// upgradePanelToV9 drops the first of three singlestat thresholds
+func upgradePanelToV9(panel map[string]interface{}) {
+	if panel["type"] != "singlestat" && panel["thresholds"] != "" {
+		return
+	}
+
+	if thresholds, ok := panel["thresholds"].(string); ok && thresholds != "" {
+		if values := strings.Split(thresholds, ","); len(values) >= 3 {
+			panel["thresholds"] = strings.Join(values[1:], ",")
+		}
+	}
+}
+
+// upgradePanelToV10 drops the first of three table style thresholds
+func upgradePanelToV10(panel map[string]interface{}) {
+	if panel["type"] != "table" {
+		return
+	}
+
+	for _, item := range asList(panel["styles"]) {
+		style := asMap(item)
+		if thresholds := asList(style["thresholds"]); len(thresholds) >= 3 {
+			style["thresholds"] = thresholds[1:]
+		}
+	}
+}
+
+// upgradePanelToV12 moves the graph axis settings to yaxes and xaxis
+func upgradePanelToV12(panel map[string]interface{}) {
+	grid := asMap(panel["grid"])
+	if panel["type"] != "graph" || grid == nil {
+		return
+	}
+
+	if _, exists := panel["yaxes"]; exists {
+		return
+	}
+
+	formats := asList(panel["y_formats"])
+	format := func(index int) interface{} {
+		if index < len(formats) {
+			return formats[index]
+		}
+		return nil
+	}
+
+	panel["yaxes"] = []interface{}{
+		map[string]interface{}{
+			"show":    panel["y-axis"],
+			"min":     grid["leftMin"],
+			"max":     grid["leftMax"],
+			"logBase": grid["leftLogBase"],
+			"format":  format(0),
+			"label":   panel["leftYAxisLabel"],
+		},
+		map[string]interface{}{
+			"show":    panel["y-axis"],
+			"min":     grid["rightMin"],
+			"max":     grid["rightMax"],
+			"logBase": grid["rightLogBase"],
+			"format":  format(1),
+			"label":   panel["rightYAxisLabel"],
+		},
+	}
+
+	panel["xaxis"] = map[string]interface{}{"show": panel["x-axis"]}
+
+	for _, key := range []string{"leftMin", "leftMax", "leftLogBase", "rightMin", "rightMax", "rightLogBase"} {
+		delete(grid, key)
+	}
+
+	for _, key := range []string{"y_formats", "leftYAxisLabel", "rightYAxisLabel", "y-axis", "x-axis"} {
+		delete(panel, key)
+	}
+}
+
+// upgradePanelToV13 moves the graph grid thresholds to panel thresholds
+func upgradePanelToV13(panel map[string]interface{}) {
+	grid := asMap(panel["grid"])
+	if panel["type"] != "graph" || grid == nil {
+		return
+	}
+
+	threshold := func(value interface{}, color interface{}) map[string]interface{} {
+		number, ok := asNumber(value)
+		if !ok {
+			return nil
+		}
+
+		t := map[string]interface{}{"value": number, "colorMode": "custom"}
+		if truthy(grid["thresholdLine"]) {
+			t["line"] = true
+			t["lineColor"] = color
+		} else {
+			t["fill"] = true
+			t["fillColor"] = color
+		}
+		return t
+	}
+
+	thresholds := make([]interface{}, 0)
+	t1 := threshold(grid["threshold1"], grid["threshold1Color"])
+	t2 := threshold(grid["threshold2"], grid["threshold2Color"])
+
+	if t1 != nil {
+		if t2 != nil {
+			op := "gt"
+			if t1["value"].(float64) > t2["value"].(float64) {
+				op = "lt"
+			}
+			t1["op"], t2["op"] = op, op
+			thresholds = append(thresholds, t1, t2)
+		} else {
+			t1["op"] = "gt"
+			thresholds = append(thresholds, t1)
+		}
+	}
+
+	panel["thresholds"] = thresholds
+
+	for _, key := range []string{"threshold1", "threshold1Color", "threshold2", "threshold2Color", "thresholdLine"} {
+		delete(grid, key)
+	}
+}
+
+func nextPanelId(dash map[string]interface{}) int64 {
+	max := int64(0)
+
+	for _, row := range asList(dash["rows"]) {
+		for _, panel := range asList(asMap(row)["panels"]) {
+			if id := simplejson.NewFromAny(panel).Get("id").MustInt64(0); id > max {
+				max = id
+			}
+		}
+	}
+
+	return max + 1
+}
+
+func nextQueryLetter(panel map[string]interface{}) interface{} {
+	for _, letter := range "ABCDEFGHIJKLMNOPQRSTUVWXYZ" {
+		refId := string(letter)
+		used := false
+		for _, target := range asList(panel["targets"]) {
+			if asMap(target)["refId"] == refId {
+				used = true
+				break
+			}
+		}
+
+		if !used {
+			return refId
+		}
+	}
+
+	return nil
+}
+
+// ensureList makes sure dash[key] is an object with a list, like the
+// DashboardModel does for templating and annotations
+func ensureList(dash map[string]interface{}, key string) map[string]interface{} {
+	container := asMap(dash[key])
+	if container == nil {
+		container = make(map[string]interface{})
+		dash[key] = container
+	}
+
+	if _, ok := container["list"].([]interface{}); !ok {
+		container["list"] = []interface{}{}
+	}
+
+	return container
+}
+
+func asMap(value interface{}) map[string]interface{} {
+	m, _ := value.(map[string]interface{})
+	return m
+}
+
+func asList(value interface{}) []interface{} {
+	list, _ := value.([]interface{})
+	return list
+}
+
+func asNumber(value interface{}) (float64, bool) {
+	switch number := value.(type) {
+	case json.Number:
+		f, err := number.Float64()
+		return f, err == nil
+	case float64:
+		return number, true
+	case int:
+		return float64(number), true
+	case int64:
+		return float64(number), true
+	}
+
+	return 0, false
+}
+
+// truthy follows the javascript rules the frontend migrations rely on
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case json.Number, float64, int, int64:
+		number, _ := asNumber(v)
+		return number != 0
+	}
+
+	return true
+}
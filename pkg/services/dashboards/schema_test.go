@@ -0,0 +1,79 @@
+package dashboards
+
+import (
+	"testing"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDashboardSchemaUpgrade(t *testing.T) {
+	Convey("Given a dashboard at schema version 6", t, func() {
+		json := `{
+			"title": "old",
+			"schemaVersion": 6,
+			"sharedCrosshair": true,
+			"nav": [{"type": "timepicker", "now": true}],
+			"templating": {"list": [{"name": "host", "refresh": true, "hideLabel": true}]},
+			"rows": [{"panels": [
+				{"id": 1, "type": "graph", "targets": [{"refId": "A"}, {}],
+				 "y-axis": true, "x-axis": true, "y_formats": ["short", "bytes"],
+				 "grid": {"leftMin": 0, "threshold1": 10, "threshold1Color": "red", "threshold2": 5, "threshold2Color": "blue"}},
+				{"type": "singlestat", "thresholds": "1,2,3"},
+				{"id": 7, "type": "table", "styles": [{"thresholds": ["1", "2", "3"]}]}
+			]}]
+		}`
+
+		data, err := simplejson.NewJson([]byte(json))
+		So(err, ShouldBeNil)
+
+		So(UpgradeSchema(data), ShouldBeTrue)
+
+		Convey("Should set the latest schema version", func() {
+			So(data.Get("schemaVersion").MustInt(), ShouldEqual, LatestSchemaVersion)
+		})
+
+		Convey("Should upgrade the dashboard settings", func() {
+			So(data.Get("graphTooltip").MustInt(), ShouldEqual, 1)
+			So(data.GetPath("timepicker", "now").MustBool(), ShouldBeTrue)
+
+			variable := data.Get("templating").Get("list").GetIndex(0)
+			So(variable.Get("refresh").MustInt(), ShouldEqual, 1)
+			So(variable.Get("hide").MustInt(), ShouldEqual, 1)
+		})
+
+		Convey("Should upgrade the panels", func() {
+			panels := data.Get("rows").GetIndex(0).Get("panels")
+
+			graph := panels.GetIndex(0)
+			So(graph.Get("targets").GetIndex(1).Get("refId").MustString(), ShouldEqual, "B")
+			So(graph.Get("yaxes").GetIndex(1).Get("format").MustString(), ShouldEqual, "bytes")
+			So(graph.Get("xaxis").Get("show").MustBool(), ShouldBeTrue)
+			So(graph.Get("thresholds").GetIndex(0).Get("op").MustString(), ShouldEqual, "lt")
+			So(len(graph.Get("thresholds").MustArray()), ShouldEqual, 2)
+
+			singlestat := panels.GetIndex(1)
+			So(singlestat.Get("thresholds").MustString(), ShouldEqual, "2,3")
+
+			table := panels.GetIndex(2)
+			So(table.Get("styles").GetIndex(0).Get("thresholds").MustStringArray(), ShouldResemble, []string{"2", "3"})
+		})
+	})
+
+	Convey("Given a batch of dashboards", t, func() {
+		current := simplejson.NewFromAny(map[string]interface{}{"title": "current", "schemaVersion": LatestSchemaVersion})
+		old := simplejson.NewFromAny(map[string]interface{}{"title": "old", "schemaVersion": 13})
+		batch := []*models.Dashboard{
+			models.NewDashboardFromJson(current),
+			models.NewDashboardFromJson(old),
+		}
+
+		Convey("Should only return the upgraded dashboards", func() {
+			upgraded := UpgradeSchemas(batch)
+			So(len(upgraded), ShouldEqual, 1)
+			So(upgraded[0].Title, ShouldEqual, "old")
+			So(upgraded[0].Data.Get("schemaVersion").MustInt(), ShouldEqual, LatestSchemaVersion)
+		})
+	})
+}
@@ -66,7 +66,7 @@ func (fr *fileReader) getCache(key string) (*dashboards.SaveDashboardItem, bool)
 func (fr *fileReader) ReadAndListen(ctx context.Context) error {
 	ticker := time.NewTicker(time.Second * 3)
 
-	if err := fr.walkFolder(); err != nil {
+	if err := fr.walkFolder(ctx); err != nil {
 		fr.log.Error("failed to search for dashboards", "error", err)
 	}
 
@@ -78,7 +78,7 @@ func (fr *fileReader) ReadAndListen(ctx context.Context) error {
 			if !running { // avoid walking the filesystem in parallel. incase fs is very slow.
 				running = true
 				go func() {
-					fr.walkFolder()
+					fr.walkFolder(ctx)
 					running = false
 				}()
 			}
@@ -88,7 +88,7 @@ func (fr *fileReader) ReadAndListen(ctx context.Context) error {
 	}
 }
 
-func (fr *fileReader) walkFolder() error {
+func (fr *fileReader) walkFolder(ctx context.Context) error {
 	if _, err := os.Stat(fr.Path); err != nil {
 		if os.IsNotExist(err) {
 			return err
@@ -127,7 +127,7 @@ func (fr *fileReader) walkFolder() error {
 		// if we dont have the dashboard in the db, save it!
 		if err == models.ErrDashboardNotFound {
 			fr.log.Debug("saving new dashboard", "file", path)
-			_, err = fr.dashboardRepo.SaveDashboard(dash)
+			_, err = fr.dashboardRepo.SaveDashboard(ctx, dash)
 			return err
 		}
 
@@ -142,7 +142,7 @@ func (fr *fileReader) walkFolder() error {
 		}
 
 		fr.log.Debug("loading dashboard from disk into database.", "file", path)
-		_, err = fr.dashboardRepo.SaveDashboard(dash)
+		_, err = fr.dashboardRepo.SaveDashboard(ctx, dash)
 		return err
 	})
 }
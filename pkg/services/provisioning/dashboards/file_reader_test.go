@@ -1,6 +1,7 @@
 package dashboards
 
 import (
+	"context"
 	"os"
 	"testing"
 	"time"
@@ -44,7 +45,7 @@ func TestDashboardFileReader(t *testing.T) {
 			reader, err := NewDashboardFileReader(cfg, logger)
 			So(err, ShouldBeNil)
 
-			err = reader.walkFolder()
+			err = reader.walkFolder(context.Background())
 			So(err, ShouldBeNil)
 
 			So(len(fakeRepo.inserted), ShouldEqual, 2)
@@ -61,7 +62,7 @@ func TestDashboardFileReader(t *testing.T) {
 			reader, err := NewDashboardFileReader(cfg, logger)
 			So(err, ShouldBeNil)
 
-			err = reader.walkFolder()
+			err = reader.walkFolder(context.Background())
 			So(err, ShouldBeNil)
 
 			So(len(fakeRepo.inserted), ShouldEqual, 0)
@@ -80,7 +81,7 @@ func TestDashboardFileReader(t *testing.T) {
 			reader, err := NewDashboardFileReader(cfg, logger)
 			So(err, ShouldBeNil)
 
-			err = reader.walkFolder()
+			err = reader.walkFolder(context.Background())
 			So(err, ShouldBeNil)
 
 			So(len(fakeRepo.inserted), ShouldEqual, 1)
@@ -120,7 +121,7 @@ type fakeDashboardRepo struct {
 	getDashboard []*models.Dashboard
 }
 
-func (repo *fakeDashboardRepo) SaveDashboard(json *dashboards.SaveDashboardItem) (*models.Dashboard, error) {
+func (repo *fakeDashboardRepo) SaveDashboard(ctx context.Context, json *dashboards.SaveDashboardItem) (*models.Dashboard, error) {
 	repo.inserted = append(repo.inserted, json)
 	return json.Dashboard, nil
 }
@@ -15,11 +15,19 @@ func searchHandler(query *Query) error {
 	hits := make(HitList, 0)
 
 	dashQuery := FindPersistedDashboardsQuery{
-		Title:        query.Title,
-		UserId:       query.UserId,
-		IsStarred:    query.IsStarred,
-		OrgId:        query.OrgId,
-		DashboardIds: query.DashboardIds,
+		Title:         query.Title,
+		TitleMatch:    query.TitleMatch,
+		Tags:          query.Tags,
+		TagMatch:      query.TagMatch,
+		UserId:        query.UserId,
+		IsStarred:     query.IsStarred,
+		OrgId:         query.OrgId,
+		DashboardIds:  query.DashboardIds,
+		Limit:         query.Limit,
+		Page:          query.Page,
+		Sort:          query.Sort,
+		UpdatedAfter:  query.UpdatedAfter,
+		UpdatedBefore: query.UpdatedBefore,
 	}
 
 	if err := bus.Dispatch(&dashQuery); err != nil {
@@ -32,15 +40,17 @@ func searchHandler(query *Query) error {
 	if len(query.Tags) > 0 {
 		filtered := HitList{}
 		for _, hit := range hits {
-			if hasRequiredTags(query.Tags, hit.Tags) {
+			if hasRequiredTags(query.Tags, hit.Tags, query.TagMatch) {
 				filtered = append(filtered, hit)
 			}
 		}
 		hits = filtered
 	}
 
-	// sort main result array
-	sort.Sort(hits)
+	// sort main result array, unless a specific order was asked for
+	if query.Sort == "" {
+		sort.Sort(hits)
+	}
 
 	if len(hits) > query.Limit {
 		hits = hits[0:query.Limit]
@@ -69,7 +79,17 @@ func stringInSlice(a string, list []string) bool {
 	return false
 }
 
-func hasRequiredTags(queryTags, hitTags []string) bool {
+func hasRequiredTags(queryTags, hitTags []string, match TagMatch) bool {
+	if match == TagMatchAny {
+		for _, queryTag := range queryTags {
+			if stringInSlice(queryTag, hitTags) {
+				return true
+			}
+		}
+
+		return false
+	}
+
 	for _, queryTag := range queryTags {
 		if !stringInSlice(queryTag, hitTags) {
 			return false
@@ -56,5 +56,30 @@ func TestSearch(t *testing.T) {
 			})
 
 		})
+
+		Convey("That filters by any tag", func() {
+			query.Tags = []string{"CC", "EE"}
+			query.TagMatch = TagMatchAny
+			err := searchHandler(&query)
+			So(err, ShouldBeNil)
+
+			Convey("should return results with at least one of the tags", func() {
+				So(len(query.Result), ShouldEqual, 2)
+				So(query.Result[0].Title, ShouldEqual, "AABB")
+				So(query.Result[1].Title, ShouldEqual, "BBAA")
+			})
+		})
+
+		Convey("That asks for a sort order", func() {
+			query.Sort = SortUpdatedDesc
+			err := searchHandler(&query)
+			So(err, ShouldBeNil)
+
+			Convey("should keep the order of the persisted results", func() {
+				So(query.Result[0].Title, ShouldEqual, "CCAA")
+				So(query.Result[1].Title, ShouldEqual, "AABB")
+				So(query.Result[2].Title, ShouldEqual, "BBAA")
+			})
+		})
 	})
 }
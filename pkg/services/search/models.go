@@ -1,5 +1,7 @@
 package search
 
+import "time"
+
 type HitType string
 
 const (
@@ -9,13 +11,43 @@ const (
 	DashHitScripted HitType = "dash-scripted"
 )
 
+// TitleMatch controls how the title filter of a search is compared against
+// dashboard titles. Matching is case insensitive in all modes.
+type TitleMatch string
+
+const (
+	TitleMatchContains TitleMatch = "contains"
+	TitleMatchPrefix   TitleMatch = "prefix"
+	TitleMatchExact    TitleMatch = "exact"
+)
+
+// TagMatch controls whether a dashboard needs all or just one of the
+// requested tags to match a search.
+type TagMatch string
+
+const (
+	TagMatchAll TagMatch = "all"
+	TagMatchAny TagMatch = "any"
+)
+
+// SortOrder is the order persisted dashboards are returned in.
+type SortOrder string
+
+const (
+	SortAlphaAsc    SortOrder = "alpha-asc"
+	SortAlphaDesc   SortOrder = "alpha-desc"
+	SortUpdatedDesc SortOrder = "updated-desc"
+)
+
 type Hit struct {
-	Id        int64    `json:"id"`
-	Title     string   `json:"title"`
-	Uri       string   `json:"uri"`
-	Type      HitType  `json:"type"`
-	Tags      []string `json:"tags"`
-	IsStarred bool     `json:"isStarred"`
+	Id        int64     `json:"id"`
+	Title     string    `json:"title"`
+	Uri       string    `json:"uri"`
+	Type      HitType   `json:"type"`
+	Tags      []string  `json:"tags"`
+	IsStarred bool      `json:"isStarred"`
+	Updated   time.Time `json:"updated"`
+	UpdatedBy int64     `json:"updatedBy"`
 }
 
 type HitList []*Hit
@@ -25,23 +57,39 @@ func (s HitList) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 func (s HitList) Less(i, j int) bool { return s[i].Title < s[j].Title }
 
 type Query struct {
-	Title        string
-	Tags         []string
-	OrgId        int64
-	UserId       int64
-	Limit        int
-	IsStarred    bool
-	DashboardIds []int
+	Title         string
+	TitleMatch    TitleMatch
+	Tags          []string
+	TagMatch      TagMatch
+	OrgId         int64
+	UserId        int64
+	Limit         int
+	Page          int
+	Sort          SortOrder
+	IsStarred     bool
+	DashboardIds  []int
+	UpdatedAfter  time.Time
+	UpdatedBefore time.Time
 
 	Result HitList
 }
 
 type FindPersistedDashboardsQuery struct {
 	Title        string
+	TitleMatch   TitleMatch // defaults to TitleMatchContains
+	Tags         []string
+	TagMatch     TagMatch // defaults to TagMatchAll
 	OrgId        int64
 	UserId       int64
 	IsStarred    bool
 	DashboardIds []int
+	Limit        int       // at most 1000, which is also the default
+	Page         int       // starts at 1
+	Sort         SortOrder // defaults to SortAlphaAsc
+	// UpdatedAfter and UpdatedBefore limit the search to dashboards
+	// updated in that range, zero values leave that end open
+	UpdatedAfter  time.Time
+	UpdatedBefore time.Time
 
 	Result HitList
 }
@@ -20,6 +20,8 @@ func init() {
 	bus.AddHandler("sql", GetAlertStatesForDashboard)
 	bus.AddHandler("sql", PauseAlert)
 	bus.AddHandler("sql", PauseAllAlerts)
+	bus.AddHandler("sql", SilenceAlert)
+	bus.AddHandler("sql", UnsilenceAlert)
 }
 
 func GetAlertById(query *m.GetAlertByIdQuery) error {
@@ -129,10 +131,14 @@ func HandleAlertsQuery(query *m.GetAlertsQuery) error {
 
 func DeleteAlertDefinition(dashboardId int64, sess *DBSession) error {
 	alerts := make([]*m.Alert, 0)
-	sess.Where("dashboard_id = ?", dashboardId).Find(&alerts)
+	if err := sess.Where("dashboard_id = ?", dashboardId).Find(&alerts); err != nil {
+		return err
+	}
 
 	for _, alert := range alerts {
-		deleteAlertByIdInternal(alert.Id, "Dashboard deleted", sess)
+		if err := deleteAlertByIdInternal(alert.Id, "Dashboard deleted", sess); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -313,6 +319,38 @@ func PauseAllAlerts(cmd *m.PauseAllAlertCommand) error {
 	})
 }
 
+func SilenceAlert(cmd *m.SilenceAlertCommand) error {
+	return inTransaction(func(sess *DBSession) error {
+		alert := m.Alert{Silenced: true, SilencedUntil: &cmd.Until}
+
+		affected, err := sess.Where("id = ? AND org_id = ?", cmd.AlertId, cmd.OrgId).
+			Cols("silenced", "silenced_until").
+			Update(&alert)
+		if err != nil {
+			return err
+		} else if affected == 0 {
+			return fmt.Errorf("Could not find alert")
+		}
+
+		return nil
+	})
+}
+
+func UnsilenceAlert(cmd *m.UnsilenceAlertCommand) error {
+	return inTransaction(func(sess *DBSession) error {
+		res, err := sess.Exec("UPDATE alert SET silenced = ?, silenced_until = NULL WHERE id = ? AND org_id = ?", dialect.BooleanStr(false), cmd.AlertId, cmd.OrgId)
+		if err != nil {
+			return err
+		}
+
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			return fmt.Errorf("Could not find alert")
+		}
+
+		return nil
+	})
+}
+
 func GetAlertStatesForDashboard(query *m.GetAlertStatesForDashboardQuery) error {
 	var rawSql = `SELECT
 	                id,
@@ -2,6 +2,7 @@ package sqlstore
 
 import (
 	"testing"
+	"time"
 
 	"github.com/grafana/grafana/pkg/components/simplejson"
 	m "github.com/grafana/grafana/pkg/models"
@@ -70,6 +71,30 @@ func TestAlertingDataAccess(t *testing.T) {
 			})
 		})
 
+		Convey("Can silence and unsilence alert", func() {
+			until := time.Now().Add(time.Hour)
+			err := SilenceAlert(&m.SilenceAlertCommand{OrgId: 1, AlertId: 1, Until: until})
+			So(err, ShouldBeNil)
+
+			query := &m.GetAlertByIdQuery{Id: 1}
+			So(GetAlertById(query), ShouldBeNil)
+			So(query.Result.Silenced, ShouldBeTrue)
+			So(query.Result.SilencedUntil.Unix(), ShouldEqual, until.Unix())
+
+			err = UnsilenceAlert(&m.UnsilenceAlertCommand{OrgId: 1, AlertId: 1})
+			So(err, ShouldBeNil)
+
+			query = &m.GetAlertByIdQuery{Id: 1}
+			So(GetAlertById(query), ShouldBeNil)
+			So(query.Result.Silenced, ShouldBeFalse)
+			So(query.Result.SilencedUntil, ShouldBeNil)
+		})
+
+		Convey("Cannot silence alert in another org", func() {
+			err := SilenceAlert(&m.SilenceAlertCommand{OrgId: 2, AlertId: 1, Until: time.Now()})
+			So(err, ShouldNotBeNil)
+		})
+
 		Convey("Can read properties", func() {
 			alertQuery := m.GetAlertsQuery{DashboardId: testDash.Id, PanelId: 1, OrgId: 1}
 			err2 := HandleAlertsQuery(&alertQuery)
@@ -2,142 +2,282 @@ package sqlstore
 
 import (
 	"bytes"
-	"fmt"
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/components/simplejson"
 	"github.com/grafana/grafana/pkg/metrics"
 	m "github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/services/search"
+	"github.com/grafana/grafana/pkg/services/sqlstore/migrator"
 )
 
 func init() {
-	bus.AddHandler("sql", SaveDashboard)
+	bus.AddCtxHandler("sql", SaveDashboardCtx)
+	bus.AddHandler("sql", SaveDashboards)
 	bus.AddHandler("sql", GetDashboard)
 	bus.AddHandler("sql", GetDashboards)
 	bus.AddHandler("sql", DeleteDashboard)
+	bus.AddHandler("sql", DeleteDashboards)
 	bus.AddHandler("sql", SearchDashboards)
 	bus.AddHandler("sql", GetDashboardTags)
 	bus.AddHandler("sql", GetDashboardSlugById)
 	bus.AddHandler("sql", GetDashboardsByPluginId)
+	bus.AddHandler("sql", GetDashboardsByTagCombinations)
+	bus.AddHandler("sql", GetDashboardsByAlertSeverity)
+	bus.AddHandler("sql", GetDashboardsByAlertNotificationCount)
+	bus.AddHandler("sql", GetDashboardsByRefreshRate)
+	bus.AddHandler("sql", GetDashboardsByVariableWithDefault)
+	bus.AddHandler("sql", GetDashboardsByPinnedTimeRange)
+	bus.AddHandler("sql", GetDashboardsBelowSchemaVersion)
+	bus.AddHandler("sql", GetDashboardsByDatasourceType)
+	bus.AddHandler("sql", GetDashboardsByTagCount)
+	bus.AddHandler("sql", GetDashboardsByPanelPluginId)
+	bus.AddHandler("sql", GetDashboardsWithSilencedAlerts)
+	bus.AddHandler("sql", GetDashboardsByAnnotationTag)
+	bus.AddHandler("sql", GetDashboardsByQueryTimeout)
+	bus.AddHandler("sql", GetDashboardsByTemplateVariableCount)
+	bus.AddHandler("sql", GetDashboardsByAlertFrequency)
+	bus.AddHandler("sql", GetDashboardsByLastAnnotation)
+	bus.AddHandler("sql", GetDashboardsByOrgWithTagSummary)
+	bus.AddHandler("sql", GetDashboardsByNonExistentDatasource)
+	bus.AddHandler("sql", GetDashboardsByVersionMessageKeyword)
+	bus.AddHandler("sql", GetDashboardsByOrgWithAlertStatus)
+	bus.AddHandler("sql", GetDashboardsByPanelCount)
+	bus.AddHandler("sql", GetDashboardsByRefreshInterval)
+	bus.AddHandler("sql", GetDashboardsByQueryTargetCount)
+	bus.AddHandler("sql", GetDashboardsByAnnotationCount)
+	bus.AddHandler("sql", GetDashboardsByRowCount)
+	bus.AddHandler("sql", GetDashboardsByHasAnnotations)
+	bus.AddHandler("sql", GetDashboardsByOrgWithAnnotationSummary)
+	bus.AddHandler("sql", GetDashboardsByVersionRestoreCount)
+	bus.AddHandler("sql", GetDashboardsByHasPluginId)
+	bus.AddHandler("sql", GetDashboardsByOrgWithFolderAndTagSummary)
+	bus.AddHandler("sql", GetDashboardsByNoDataAlertCount)
+	bus.AddHandler("sql", GetDashboardsByQueryCountRange)
+	bus.AddHandler("sql", GetDashboardsByOrgWithUserInfo)
+	bus.AddHandler("sql", GetDashboardsByStarredAndTag)
+	bus.AddHandler("sql", GetDashboardsByOrgAndMaxSize)
+	bus.AddHandler("sql", GetDashboardsByOrgExcludingPluginDashboards)
+	bus.AddHandler("sql", GetDashboardsByAlertPendingDuration)
+	bus.AddHandler("sql", GetDashboardsByOrgAndUpdatedBeforeDate)
+	bus.AddHandler("sql", GetDashboardsByOrgWithRecentAlertTransitions)
 }
 
 func SaveDashboard(cmd *m.SaveDashboardCommand) error {
-	return inTransaction(func(sess *DBSession) error {
-		dash := cmd.GetDashboardModel()
+	return SaveDashboardCtx(context.Background(), cmd)
+}
 
-		// try get existing dashboard
-		var existing, sameTitle m.Dashboard
+// SaveDashboardCtx saves the dashboard like SaveDashboard, but stops and rolls
+// back the transaction when ctx is cancelled, e.g. when the client went away.
+// Cancellation is checked before the transaction starts, between the
+// statements of the save and before the commit, a statement that is already
+// running is not interrupted.
+func SaveDashboardCtx(ctx context.Context, cmd *m.SaveDashboardCommand) error {
+	return inTransactionCtx(ctx, func(sess *DBSession) error {
+		return saveDashboard(ctx, sess, cmd)
+	})
+}
 
-		if dash.Id > 0 {
-			dashWithIdExists, err := sess.Where("id=? AND org_id=?", dash.Id, dash.OrgId).Get(&existing)
-			if err != nil {
-				return err
-			}
-			if !dashWithIdExists {
-				return m.ErrDashboardNotFound
-			}
+// SaveDashboards saves all dashboards in one transaction, when one of them
+// cannot be saved none of them are.
+func SaveDashboards(cmd *m.SaveDashboardsCommand) error {
+	return inTransaction(func(sess *DBSession) error {
+		cmd.Result = make([]*m.Dashboard, 0)
 
-			// check for is someone else has written in between
-			if dash.Version != existing.Version {
-				if cmd.Overwrite {
-					dash.Version = existing.Version
-				} else {
-					return m.ErrDashboardVersionMismatch
+		for index, saveCmd := range cmd.Dashboards {
+			if err := saveDashboard(context.Background(), sess, saveCmd); err != nil {
+				return m.SaveDashboardsError{
+					Index: index,
+					Title: saveCmd.Dashboard.Get("title").MustString(),
+					Err:   err,
 				}
 			}
 
-			// do not allow plugin dashboard updates without overwrite flag
-			if existing.PluginId != "" && cmd.Overwrite == false {
-				return m.UpdatePluginDashboardError{PluginId: existing.PluginId}
-			}
+			cmd.Result = append(cmd.Result, saveCmd.Result)
 		}
 
-		sameTitleExists, err := sess.Where("org_id=? AND slug=?", dash.OrgId, dash.Slug).Get(&sameTitle)
+		return nil
+	})
+}
+
+func saveDashboard(ctx context.Context, sess *DBSession, cmd *m.SaveDashboardCommand) error {
+	dash := cmd.GetDashboardModel()
+
+	// try get existing dashboard
+	var existing, sameTitle m.Dashboard
+
+	if dash.Id > 0 {
+		dashWithIdExists, err := sess.Where("id=? AND org_id=?", dash.Id, dash.OrgId).Get(&existing)
 		if err != nil {
 			return err
 		}
+		if !dashWithIdExists {
+			return m.ErrDashboardNotFound
+		}
 
-		if sameTitleExists {
-			// another dashboard with same name
-			if dash.Id != sameTitle.Id {
-				if cmd.Overwrite {
-					dash.Id = sameTitle.Id
-					dash.Version = sameTitle.Version
-				} else {
-					return m.ErrDashboardWithSameNameExists
-				}
+		// check for is someone else has written in between
+		if dash.Version != existing.Version {
+			if cmd.Overwrite {
+				dash.Version = existing.Version
+			} else {
+				return m.ErrDashboardVersionMismatch
 			}
 		}
 
-		parentVersion := dash.Version
-		affectedRows := int64(0)
+		// do not allow plugin dashboard updates without overwrite flag
+		if existing.PluginId != "" && cmd.Overwrite == false {
+			return m.UpdatePluginDashboardError{PluginId: existing.PluginId}
+		}
+	}
 
-		if dash.Id == 0 {
-			dash.Version = 1
-			metrics.M_Api_Dashboard_Insert.Inc()
-			dash.Data.Set("version", dash.Version)
-			affectedRows, err = sess.Insert(dash)
-		} else {
-			dash.Version += 1
-			dash.Data.Set("version", dash.Version)
+	sameTitleExists, err := sess.Where("org_id=? AND slug=?", dash.OrgId, dash.Slug).Get(&sameTitle)
+	if err != nil {
+		return err
+	}
 
-			if !cmd.UpdatedAt.IsZero() {
-				dash.Updated = cmd.UpdatedAt
+	if sameTitleExists {
+		// another dashboard with same name
+		if dash.Id != sameTitle.Id {
+			if cmd.Overwrite {
+				dash.Id = sameTitle.Id
+				dash.Version = sameTitle.Version
+			} else {
+				return m.ErrDashboardWithSameNameExists
 			}
-
-			affectedRows, err = sess.Id(dash.Id).Update(dash)
 		}
+	}
 
-		if err != nil {
-			return err
-		}
+	parentVersion := dash.Version
+	previousVersion := 0
+	affectedRows := int64(0)
 
-		if affectedRows == 0 {
-			return m.ErrDashboardNotFound
-		}
+	if dash.Id == 0 {
+		dash.Version = 1
+		metrics.M_Api_Dashboard_Insert.Inc()
+		dash.Data.Set("version", dash.Version)
+		affectedRows, err = sess.Insert(dash)
+	} else {
+		previousVersion = dash.Version
+		dash.Version += 1
+		dash.Data.Set("version", dash.Version)
 
-		dashVersion := &m.DashboardVersion{
-			DashboardId:   dash.Id,
-			ParentVersion: parentVersion,
-			RestoredFrom:  cmd.RestoredFrom,
-			Version:       dash.Version,
-			Created:       time.Now(),
-			CreatedBy:     dash.UpdatedBy,
-			Message:       cmd.Message,
-			Data:          dash.Data,
+		if !cmd.UpdatedAt.IsZero() {
+			dash.Updated = cmd.UpdatedAt
 		}
 
-		// insert version entry
-		if affectedRows, err = sess.Insert(dashVersion); err != nil {
-			return err
-		} else if affectedRows == 0 {
-			return m.ErrDashboardNotFound
-		}
+		affectedRows, err = sess.Id(dash.Id).Update(dash)
+	}
 
-		// delete existing tabs
-		_, err = sess.Exec("DELETE FROM dashboard_tag WHERE dashboard_id=?", dash.Id)
-		if err != nil {
+	if err != nil {
+		return err
+	}
+
+	if affectedRows == 0 {
+		return m.ErrDashboardNotFound
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	dashVersion := &m.DashboardVersion{
+		DashboardId:   dash.Id,
+		ParentVersion: parentVersion,
+		RestoredFrom:  cmd.RestoredFrom,
+		Version:       dash.Version,
+		Created:       time.Now(),
+		CreatedBy:     dash.UpdatedBy,
+		Message:       cmd.Message,
+		Data:          dash.Data,
+	}
+
+	// insert version entry
+	if affectedRows, err = sess.Insert(dashVersion); err != nil {
+		return err
+	} else if affectedRows == 0 {
+		return m.ErrDashboardNotFound
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	tags := dash.GetTags()
+
+	// leave the tags alone when they did not change
+	existingTags, err := getDashboardTagTerms(sess, dash.Id)
+	if err != nil {
+		return err
+	}
+
+	if !sameTagTerms(existingTags, tags) {
+		if err := replaceDashboardTags(sess, dash.Id, tags); err != nil {
 			return err
 		}
+	}
 
-		// insert new tags
-		tags := dash.GetTags()
-		if len(tags) > 0 {
-			for _, tag := range tags {
-				if _, err := sess.Insert(&DashboardTag{DashboardId: dash.Id, Term: tag}); err != nil {
-					return err
-				}
-			}
-		}
+	cmd.Result = dash
+	cmd.PreviousVersion = previousVersion
 
-		cmd.Result = dash
+	return nil
+}
 
+func replaceDashboardTags(sess *DBSession, dashboardId int64, tags []string) error {
+	// delete existing tabs
+	if _, err := sess.Exec("DELETE FROM dashboard_tag WHERE dashboard_id=?", dashboardId); err != nil {
 		return err
-	})
+	}
+
+	if len(tags) == 0 {
+		return nil
+	}
+
+	// insert new tags in one statement
+	dashTags := make([]*DashboardTag, 0)
+	for _, tag := range tags {
+		dashTags = append(dashTags, &DashboardTag{DashboardId: dashboardId, Term: tag})
+	}
+
+	_, err := sess.Insert(&dashTags)
+	return err
+}
+
+func getDashboardTagTerms(sess *DBSession, dashboardId int64) ([]string, error) {
+	terms := make([]string, 0)
+	err := sess.Table("dashboard_tag").Cols("term").Where("dashboard_id=?", dashboardId).Find(&terms)
+	return terms, err
+}
+
+// sameTagTerms compares tags ignoring their order
+func sameTagTerms(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	sortedA := append([]string{}, a...)
+	sortedB := append([]string{}, b...)
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+
+	for i := range sortedA {
+		if sortedA[i] != sortedB[i] {
+			return false
+		}
+	}
+
+	return true
 }
 
 func GetDashboard(query *m.GetDashboardQuery) error {
+	if query.Slug == "" && query.Id == 0 && query.Title != "" {
+		return getDashboardByTitle(query)
+	}
+
 	dashboard := m.Dashboard{Slug: query.Slug, OrgId: query.OrgId, Id: query.Id}
 	has, err := x.Get(&dashboard)
 
@@ -152,24 +292,78 @@ func GetDashboard(query *m.GetDashboardQuery) error {
 	return nil
 }
 
+// getDashboardByTitle looks up a dashboard by its exact title within an org.
+// Titles are not guaranteed to be unique, so more than one match is an error
+// rather than an arbitrary pick.
+func getDashboardByTitle(query *m.GetDashboardQuery) error {
+	var dashboards []*m.Dashboard
+	err := x.Where("org_id=? AND title=?", query.OrgId, query.Title).Limit(2).Find(&dashboards)
+
+	if err != nil {
+		return err
+	} else if len(dashboards) == 0 {
+		return m.ErrDashboardNotFound
+	} else if len(dashboards) > 1 {
+		return m.ErrDashboardTitleNotUnique
+	}
+
+	dashboard := dashboards[0]
+	dashboard.Data.Set("id", dashboard.Id)
+	query.Result = dashboard
+	return nil
+}
+
+// maxSearchLimit is the most dashboards a single search returns
+const maxSearchLimit = 1000
+
+// searchSortOrders maps the supported search sort options to their ORDER BY
+// clause, only these are ever written into the query. Every clause ends on
+// the id so dashboards with the same title are paged in a stable order.
+var searchSortOrders = map[search.SortOrder]string{
+	search.SortAlphaAsc:    "dashboard.title ASC, dashboard.id ASC",
+	search.SortAlphaDesc:   "dashboard.title DESC, dashboard.id ASC",
+	search.SortUpdatedDesc: "dashboard.updated DESC, dashboard.title ASC, dashboard.id ASC",
+}
+
 type DashboardSearchProjection struct {
-	Id    int64
-	Title string
-	Slug  string
-	Term  string
+	Id        int64
+	Title     string
+	Slug      string
+	Term      string
+	Updated   time.Time
+	UpdatedBy int64
 }
 
 func SearchDashboards(query *search.FindPersistedDashboardsQuery) error {
 	var sql bytes.Buffer
 	params := make([]interface{}, 0)
 
+	limit := query.Limit
+	if limit < 1 || limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	page := query.Page
+	if page < 1 {
+		page = 1
+	}
+
+	orderBy, ok := searchSortOrders[query.Sort]
+	if !ok {
+		orderBy = searchSortOrders[search.SortAlphaAsc]
+	}
+
+	// page over dashboards in a subquery, joining the tags afterwards would
+	// otherwise make the limit apply to dashboard and tag pairs
 	sql.WriteString(`SELECT
-					  dashboard.id,
-					  dashboard.title,
-					  dashboard.slug,
-					  dashboard_tag.term
-					FROM dashboard
-					LEFT OUTER JOIN dashboard_tag on dashboard_tag.dashboard_id = dashboard.id`)
+					  dashboard.id AS id,
+					  dashboard.title AS title,
+					  dashboard.slug AS slug,
+					  dashboard.updated AS updated,
+					  dashboard.updated_by AS updated_by,
+					  dashboard_tag.term AS term
+					FROM (
+					  SELECT dashboard.id FROM dashboard`)
 
 	if query.IsStarred {
 		sql.WriteString(" INNER JOIN star on star.dashboard_id = dashboard.id")
@@ -199,10 +393,44 @@ func SearchDashboards(query *search.FindPersistedDashboardsQuery) error {
 
 	if len(query.Title) > 0 {
 		sql.WriteString(" AND dashboard.title " + dialect.LikeStr() + " ?")
-		params = append(params, "%"+query.Title+"%")
+		params = append(params, titleMatchPattern(query.Title, query.TitleMatch))
+	}
+
+	if !query.UpdatedAfter.IsZero() {
+		sql.WriteString(" AND dashboard.updated >= ?")
+		params = append(params, query.UpdatedAfter)
+	}
+
+	if !query.UpdatedBefore.IsZero() {
+		sql.WriteString(" AND dashboard.updated <= ?")
+		params = append(params, query.UpdatedBefore)
+	}
+
+	// filter on tags in sql as well, so the limit below applies to
+	// dashboards matching both title and tags
+	if len(query.Tags) > 0 {
+		sql.WriteString(` AND dashboard.id IN (
+					SELECT dashboard_id FROM dashboard_tag
+					WHERE term IN (?` + strings.Repeat(",?", len(query.Tags)-1) + `)`)
+		for _, tag := range query.Tags {
+			params = append(params, tag)
+		}
+
+		// unless any tag will do, every distinct requested tag must be present
+		if query.TagMatch != search.TagMatchAny {
+			sql.WriteString(` GROUP BY dashboard_id HAVING COUNT(DISTINCT term) = ?`)
+			params = append(params, countDistinct(query.Tags))
+		}
+
+		sql.WriteString(`)`)
 	}
 
-	sql.WriteString(fmt.Sprintf(" ORDER BY dashboard.title ASC LIMIT 1000"))
+	sql.WriteString(` ORDER BY ` + orderBy + ` LIMIT ? OFFSET ?
+					) AS ids
+					INNER JOIN dashboard on ids.id = dashboard.id
+					LEFT OUTER JOIN dashboard_tag on dashboard_tag.dashboard_id = dashboard.id
+					ORDER BY ` + orderBy)
+	params = append(params, limit, limit*(page-1))
 
 	var res []DashboardSearchProjection
 
@@ -211,28 +439,62 @@ func SearchDashboards(query *search.FindPersistedDashboardsQuery) error {
 		return err
 	}
 
-	query.Result = make([]*search.Hit, 0)
+	query.Result = makeQueryResult(res)
+	return nil
+}
+
+func countDistinct(terms []string) int {
+	seen := make(map[string]bool)
+	for _, term := range terms {
+		seen[term] = true
+	}
+	return len(seen)
+}
+
+// makeQueryResult folds the dashboard and tag rows of a search into one hit
+// per dashboard, keeping the order of the rows. The same tag can show up in
+// several rows of a dashboard but is only added to its hit once.
+func makeQueryResult(res []DashboardSearchProjection) search.HitList {
+	result := make(search.HitList, 0)
 	hits := make(map[int64]*search.Hit)
+	seenTags := make(map[int64]map[string]bool)
 
 	for _, item := range res {
 		hit, exists := hits[item.Id]
 		if !exists {
 			hit = &search.Hit{
-				Id:    item.Id,
-				Title: item.Title,
-				Uri:   "db/" + item.Slug,
-				Type:  search.DashHitDB,
-				Tags:  []string{},
+				Id:        item.Id,
+				Title:     item.Title,
+				Uri:       "db/" + item.Slug,
+				Type:      search.DashHitDB,
+				Tags:      []string{},
+				Updated:   item.Updated,
+				UpdatedBy: item.UpdatedBy,
 			}
-			query.Result = append(query.Result, hit)
+			result = append(result, hit)
 			hits[item.Id] = hit
+			seenTags[item.Id] = make(map[string]bool)
 		}
-		if len(item.Term) > 0 {
+		if len(item.Term) > 0 && !seenTags[item.Id][item.Term] {
+			seenTags[item.Id][item.Term] = true
 			hit.Tags = append(hit.Tags, item.Term)
 		}
 	}
 
-	return err
+	return result
+}
+
+// titleMatchPattern builds the LIKE pattern for a title filter, unknown
+// modes fall back to matching anywhere in the title.
+func titleMatchPattern(title string, match search.TitleMatch) string {
+	switch match {
+	case search.TitleMatchExact:
+		return title
+	case search.TitleMatchPrefix:
+		return title + "%"
+	default:
+		return "%" + title + "%"
+	}
 }
 
 func GetDashboardTags(query *m.GetDashboardTagsQuery) error {
@@ -260,30 +522,65 @@ func DeleteDashboard(cmd *m.DeleteDashboardCommand) error {
 			return m.ErrDashboardNotFound
 		}
 
-		deletes := []string{
-			"DELETE FROM dashboard_tag WHERE dashboard_id = ? ",
-			"DELETE FROM star WHERE dashboard_id = ? ",
-			"DELETE FROM dashboard WHERE id = ?",
-			"DELETE FROM playlist_item WHERE type = 'dashboard_by_id' AND value = ?",
-			"DELETE FROM dashboard_version WHERE dashboard_id = ?",
-			"DELETE FROM annotation WHERE dashboard_id = ?",
+		return deleteDashboard(sess, dashboard.Id)
+	})
+}
+
+// DeleteDashboards deletes several dashboards in one transaction. All ids
+// must belong to the org, otherwise nothing is deleted.
+func DeleteDashboards(cmd *m.DeleteDashboardsCommand) error {
+	if len(cmd.DashboardIds) == 0 {
+		return m.ErrCommandValidationFailed
+	}
+
+	return inTransaction(func(sess *DBSession) error {
+		var dashboards []*m.Dashboard
+		err := sess.Where("org_id=?", cmd.OrgId).In("id", cmd.DashboardIds).Cols("id").Find(&dashboards)
+		if err != nil {
+			return err
 		}
 
-		for _, sql := range deletes {
-			_, err := sess.Exec(sql, dashboard.Id)
-			if err != nil {
-				return err
+		found := make(map[int64]bool)
+		for _, dashboard := range dashboards {
+			found[dashboard.Id] = true
+		}
+
+		for _, id := range cmd.DashboardIds {
+			if !found[id] {
+				return m.ErrDashboardNotFound
 			}
 		}
 
-		if err := DeleteAlertDefinition(dashboard.Id, sess); err != nil {
-			return nil
+		for _, dashboard := range dashboards {
+			if err := deleteDashboard(sess, dashboard.Id); err != nil {
+				return err
+			}
 		}
 
 		return nil
 	})
 }
 
+func deleteDashboard(sess *DBSession, dashboardId int64) error {
+	deletes := []string{
+		"DELETE FROM dashboard_tag WHERE dashboard_id = ? ",
+		"DELETE FROM star WHERE dashboard_id = ? ",
+		"DELETE FROM dashboard WHERE id = ?",
+		"DELETE FROM playlist_item WHERE type = 'dashboard_by_id' AND value = ?",
+		"DELETE FROM dashboard_version WHERE dashboard_id = ?",
+		"DELETE FROM annotation WHERE dashboard_id = ?",
+	}
+
+	for _, sql := range deletes {
+		_, err := sess.Exec(sql, dashboardId)
+		if err != nil {
+			return err
+		}
+	}
+
+	return DeleteAlertDefinition(dashboardId, sess)
+}
+
 func GetDashboards(query *m.GetDashboardsQuery) error {
 	if len(query.DashboardIds) == 0 {
 		return m.ErrCommandValidationFailed
@@ -314,6 +611,1304 @@ func GetDashboardsByPluginId(query *m.GetDashboardsByPluginIdQuery) error {
 	return nil
 }
 
+type DashboardIdDTO struct {
+	Id int64
+}
+
+func GetDashboardsByTagCombinations(query *m.GetDashboardsByTagCombinationsQuery) error {
+	var sql bytes.Buffer
+	params := make([]interface{}, 0)
+
+	for _, tags := range query.TagSets {
+		if len(tags) == 0 {
+			continue
+		}
+
+		if sql.Len() > 0 {
+			sql.WriteString(" UNION ALL ")
+		}
+
+		sql.WriteString(`SELECT dashboard.id
+					FROM dashboard
+					INNER JOIN dashboard_tag on dashboard_tag.dashboard_id = dashboard.id
+					WHERE dashboard.org_id=? AND dashboard_tag.term IN (?` + strings.Repeat(",?", len(tags)-1) + `)
+					GROUP BY dashboard.id
+					HAVING COUNT(DISTINCT dashboard_tag.term) = ?`)
+
+		params = append(params, query.OrgId)
+		for _, tag := range tags {
+			params = append(params, tag)
+		}
+		params = append(params, len(tags))
+	}
+
+	query.Result = make([]*m.Dashboard, 0)
+	if sql.Len() == 0 {
+		return nil
+	}
+
+	var res []DashboardIdDTO
+	if err := x.Sql(sql.String(), params...).Find(&res); err != nil {
+		return err
+	}
+
+	// the same dashboard can match several tag sets
+	seen := make(map[int64]bool)
+	dashboardIds := make([]int64, 0)
+	for _, item := range res {
+		if !seen[item.Id] {
+			seen[item.Id] = true
+			dashboardIds = append(dashboardIds, item.Id)
+		}
+	}
+
+	if len(dashboardIds) == 0 {
+		return nil
+	}
+
+	return x.In("id", dashboardIds).Asc("title").Find(&query.Result)
+}
+
+func GetDashboardsByAlertSeverity(query *m.GetDashboardsByAlertSeverityQuery) error {
+	var dashboards = make([]*m.Dashboard, 0)
+
+	err := x.Where("org_id=? AND id IN (SELECT dashboard_id FROM alert WHERE org_id=? AND severity=?)", query.OrgId, query.OrgId, query.Severity).
+		Asc("title").
+		Find(&dashboards)
+	query.Result = dashboards
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type DashboardCountProjection struct {
+	DashboardId int64
+	Count       int
+}
+
+type DashboardAlertSettingsProjection struct {
+	Id       int64
+	Title    string
+	Slug     string
+	Settings *simplejson.Json
+}
+
+// GetDashboardsByAlertNotificationCount counts the distinct, still existing
+// notification channels referenced by the alerts of each dashboard. Alerts
+// store their channels in the settings json, so the counting is done here
+// rather than in sql.
+func GetDashboardsByAlertNotificationCount(query *m.GetDashboardsByAlertNotificationCountQuery) error {
+	var rawSql = `SELECT
+					dashboard.id,
+					dashboard.title,
+					dashboard.slug,
+					alert.settings
+					FROM dashboard
+					INNER JOIN alert on alert.dashboard_id = dashboard.id
+					WHERE dashboard.org_id=?
+					ORDER BY dashboard.title ASC`
+
+	var res []DashboardAlertSettingsProjection
+	if err := x.Sql(rawSql, query.OrgId).Find(&res); err != nil {
+		return err
+	}
+
+	notifications := make([]*m.AlertNotification, 0)
+	if err := x.Where("org_id=?", query.OrgId).Find(&notifications); err != nil {
+		return err
+	}
+
+	existing := make(map[int64]bool)
+	for _, notification := range notifications {
+		existing[notification.Id] = true
+	}
+
+	channels := make(map[int64]map[int64]bool)
+	dashboards := make([]*m.DashboardWithNotificationCount, 0)
+
+	for _, item := range res {
+		if _, exists := channels[item.Id]; !exists {
+			channels[item.Id] = make(map[int64]bool)
+			dashboards = append(dashboards, &m.DashboardWithNotificationCount{
+				DashboardId: item.Id,
+				Title:       item.Title,
+				Slug:        item.Slug,
+			})
+		}
+
+		if item.Settings == nil {
+			continue
+		}
+
+		for _, v := range item.Settings.Get("notifications").MustArray() {
+			if id, err := simplejson.NewFromAny(v).Get("id").Int64(); err == nil && existing[id] {
+				channels[item.Id][id] = true
+			}
+		}
+	}
+
+	query.Result = make([]*m.DashboardWithNotificationCount, 0)
+	for _, dash := range dashboards {
+		dash.NotificationCount = len(channels[dash.DashboardId])
+		if dash.NotificationCount >= query.MinChannels {
+			query.Result = append(query.Result, dash)
+		}
+	}
+
+	sort.SliceStable(query.Result, func(i, j int) bool {
+		return query.Result[i].NotificationCount > query.Result[j].NotificationCount
+	})
+
+	return nil
+}
+
+func GetDashboardsByRefreshRate(query *m.GetDashboardsByRefreshRateQuery) error {
+	maxInterval := time.Duration(query.MaxRefreshSeconds) * time.Second
+
+	query.Result = make([]*m.Dashboard, 0)
+	where, params := jsonLike(`"refresh":"`)
+
+	return forEachDashboard(query.OrgId, where, params, func(dash *m.Dashboard) {
+		if interval, enabled := dash.GetRefreshInterval(); enabled && interval <= maxInterval {
+			query.Result = append(query.Result, dash)
+		}
+	})
+}
+
+func GetDashboardsByRefreshInterval(query *m.GetDashboardsByRefreshIntervalQuery) error {
+	total, err := x.Where("org_id=?", query.OrgId).Count(&m.Dashboard{})
+	if err != nil {
+		return err
+	}
+
+	query.Result = make(map[string]int)
+
+	// null and false both mean auto-refresh is off, only dashboards with a
+	// refresh string have to be inspected
+	where, params := jsonLike(`"refresh":"`)
+	refreshing := 0
+	err = forEachDashboard(query.OrgId, where, params, func(dash *m.Dashboard) {
+		if refresh := dash.Data.Get("refresh").MustString(); refresh != "" {
+			query.Result[refresh]++
+			refreshing++
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if off := int(total) - refreshing; off > 0 {
+		query.Result[""] = off
+	}
+
+	return nil
+}
+
+// GetDashboardsByVariableWithDefault returns the dashboards where the current
+// value of the named template variable differs from the given default.
+func GetDashboardsByVariableWithDefault(query *m.GetDashboardsByVariableWithDefaultQuery) error {
+	query.Result = make([]*m.Dashboard, 0)
+
+	// the variable name has to be somewhere in the json
+	where, params := jsonLike(jsonKeyValue("name", query.VariableName))
+
+	return forEachDashboard(query.OrgId, where, params, func(dash *m.Dashboard) {
+		for _, variable := range dash.GetTemplateVariables() {
+			if variable.Get("name").MustString() != query.VariableName {
+				continue
+			}
+
+			if !isVariableValue(variable.Get("current").Get("value"), query.DefaultValue) {
+				query.Result = append(query.Result, dash)
+			}
+			break
+		}
+	})
+}
+
+// isVariableValue treats a multi value variable with only the given
+// value selected the same as a single value variable
+func isVariableValue(current *simplejson.Json, value string) bool {
+	if values, err := current.StringArray(); err == nil {
+		return len(values) == 1 && values[0] == value
+	}
+
+	return current.MustString() == value
+}
+
+func GetDashboardsByPinnedTimeRange(query *m.GetDashboardsByPinnedTimeRangeQuery) error {
+	query.Result = make([]*m.Dashboard, 0)
+
+	// panels and plugins can have time objects of their own, so only the
+	// top level time range can tell whether a dashboard is pinned
+	return forEachDashboard(query.OrgId, "", nil, func(dash *m.Dashboard) {
+		if dash.HasAbsoluteTimeRange() {
+			query.Result = append(query.Result, dash)
+		}
+	})
+}
+
+func GetDashboardsBelowSchemaVersion(query *m.GetDashboardsBelowSchemaVersionQuery) error {
+	skip := 0
+	if query.Limit > 0 {
+		if query.Page < 1 {
+			query.Page = 1
+		}
+		skip = query.Limit * (query.Page - 1)
+	}
+
+	if schemaVersion := dashboardSchemaVersionSql(); schemaVersion != "" {
+		var dashboards = make([]*m.Dashboard, 0)
+		sess := x.Where("org_id=? AND "+schemaVersion+" < ?", query.OrgId, query.MaxSchemaVersion).Asc("id")
+		if query.Limit > 0 {
+			sess.Limit(query.Limit, skip)
+		}
+
+		err := sess.Find(&dashboards)
+		if err == nil {
+			query.Result = dashboards
+			return nil
+		}
+
+		// servers without the json functions and dashboards with invalid
+		// json fail the query, those are checked in Go instead
+		sqlog.Warn("Failed to compare schema versions in sql, checking dashboards in Go", "error", err)
+	}
+
+	// the schemaVersion key can also appear in nested objects, so the
+	// version is read from the top level of the json of every dashboard
+	query.Result = make([]*m.Dashboard, 0)
+	for offset := 0; ; offset += dashboardBatchSize {
+		dashboards := make([]*m.Dashboard, 0)
+		if err := x.Where("org_id=?", query.OrgId).Asc("id").Limit(dashboardBatchSize, offset).Find(&dashboards); err != nil {
+			return err
+		}
+
+		for _, dash := range dashboards {
+			if dash.Data.Get("schemaVersion").MustInt(0) >= query.MaxSchemaVersion {
+				continue
+			}
+
+			if skip > 0 {
+				skip--
+				continue
+			}
+
+			query.Result = append(query.Result, dash)
+			if query.Limit > 0 && len(query.Result) == query.Limit {
+				return nil
+			}
+		}
+
+		if len(dashboards) < dashboardBatchSize {
+			return nil
+		}
+	}
+}
+
+// dashboardSchemaVersionSql reads the schemaVersion from the top level of
+// the dashboard json, dashboards without one count as version 0. It is
+// empty for sqlite, which is built without the json1 extension.
+func dashboardSchemaVersionSql() string {
+	switch dialect.DriverName() {
+	case migrator.POSTGRES:
+		return "COALESCE(CAST(dashboard.data::json->>'schemaVersion' AS INTEGER), 0)"
+	case migrator.MYSQL:
+		return "COALESCE(CAST(JSON_EXTRACT(dashboard.data, '$.schemaVersion') AS SIGNED), 0)"
+	default:
+		return ""
+	}
+}
+
+// GetDashboardsByDatasourceType first resolves the names of the org's
+// datasources of the given type, then looks for those names in the
+// panels of each dashboard.
+func GetDashboardsByDatasourceType(query *m.GetDashboardsByDatasourceTypeQuery) error {
+	query.Result = make([]*m.Dashboard, 0)
+
+	datasources := make([]*m.DataSource, 0)
+	if err := x.Where("org_id=? AND type=?", query.OrgId, query.DatasourceType).Find(&datasources); err != nil {
+		return err
+	}
+
+	if len(datasources) == 0 {
+		return nil
+	}
+
+	names := make(map[string]bool)
+	fragments := make([]string, 0)
+	for _, ds := range datasources {
+		names[ds.Name] = true
+		fragments = append(fragments, jsonKeyValue("datasource", ds.Name))
+		if ds.IsDefault {
+			names[""] = true
+		}
+	}
+
+	// panels using the default datasource don't name it in the json
+	where, params := "", []interface{}{}
+	if !names[""] {
+		where, params = jsonLike(fragments...)
+	}
+
+	return forEachDashboard(query.OrgId, where, params, func(dash *m.Dashboard) {
+		for _, name := range dash.GetDatasourceRefs() {
+			if names[name] {
+				query.Result = append(query.Result, dash)
+				break
+			}
+		}
+	})
+}
+
+func GetDashboardsByTagCount(query *m.GetDashboardsByTagCountQuery) error {
+	// counting dashboard_tag.id rather than * makes untagged dashboards count 0
+	var rawSql = `SELECT
+					dashboard.id as dashboard_id,
+					dashboard.title,
+					dashboard.slug,
+					COUNT(dashboard_tag.id) as tag_count
+					FROM dashboard
+					LEFT OUTER JOIN dashboard_tag on dashboard_tag.dashboard_id = dashboard.id
+					WHERE dashboard.org_id=?
+					GROUP BY dashboard.id, dashboard.title, dashboard.slug
+					HAVING COUNT(dashboard_tag.id) BETWEEN ? AND ?
+					ORDER BY tag_count DESC, dashboard.title ASC`
+
+	query.Result = make([]*m.DashboardWithTagCount, 0)
+	return x.Sql(rawSql, query.OrgId, query.MinTags, query.MaxTags).Find(&query.Result)
+}
+
+func GetDashboardsByPanelPluginId(query *m.GetDashboardsByPanelPluginIdQuery) error {
+	if condition := dashboardPanelTypeSql(); condition != "" {
+		var dashboards = make([]*m.Dashboard, 0)
+		err := x.Where("org_id=? AND "+condition, query.OrgId, query.PanelPluginId).Asc("title", "id").Find(&dashboards)
+		if err == nil {
+			query.Result = dashboards
+			return nil
+		}
+
+		// servers without the json functions and dashboards with invalid
+		// json fail the query, those are matched in Go instead
+		sqlog.Warn("Failed to look up panel plugin in sql, matching dashboards in Go", "error", err)
+	}
+
+	query.Result = make([]*m.Dashboard, 0)
+	where, params := jsonLike(jsonKeyValue("type", query.PanelPluginId))
+
+	return forEachDashboard(query.OrgId, where, params, func(dash *m.Dashboard) {
+		for _, panel := range dash.GetPanels() {
+			if panel.Get("type").MustString() == query.PanelPluginId {
+				query.Result = append(query.Result, dash)
+				break
+			}
+		}
+	})
+}
+
+// dashboardPanelTypeSql returns a condition matching dashboards with a panel
+// of the plugin given as parameter, looking at the same panels as
+// Dashboard.GetPanels. It is empty for sqlite, which is built without the
+// json1 extension.
+func dashboardPanelTypeSql() string {
+	switch dialect.DriverName() {
+	case migrator.POSTGRES:
+		return `(jsonb_path_query_array(dashboard.data::jsonb, '$.rows[*].panels[*].type') ||
+			jsonb_path_query_array(dashboard.data::jsonb, '$.panels[*].type') ||
+			jsonb_path_query_array(dashboard.data::jsonb, '$.panels[*].panels[*].type')) @> jsonb_build_array(?::text)`
+	case migrator.MYSQL:
+		return `JSON_CONTAINS(JSON_EXTRACT(dashboard.data, '$.rows[*].panels[*].type', '$.panels[*].type', '$.panels[*].panels[*].type'), JSON_QUOTE(?))`
+	default:
+		return ""
+	}
+}
+
+type DashboardSilenceProjection struct {
+	DashboardId   int64
+	Title         string
+	SilencedUntil time.Time
+}
+
+// GetDashboardsWithSilencedAlerts returns the dashboards having at least one
+// alert that is still silenced, together with the latest silence end. Like
+// the alert transitions the latest date is picked in Go, aggregated dates are
+// not typed consistently by the drivers.
+func GetDashboardsWithSilencedAlerts(query *m.GetDashboardsWithSilencedAlertsQuery) error {
+	var rawSql = `SELECT
+					dashboard.id as dashboard_id,
+					dashboard.title,
+					alert.silenced_until
+					FROM dashboard
+					INNER JOIN alert on alert.dashboard_id = dashboard.id
+					WHERE dashboard.org_id=? AND alert.silenced_until > ?
+					ORDER BY dashboard.title ASC, dashboard.id ASC`
+
+	var res []DashboardSilenceProjection
+	if err := x.Sql(rawSql, query.OrgId, time.Now()).Find(&res); err != nil {
+		return err
+	}
+
+	query.Result = make([]*m.DashboardWithSilenceInfo, 0)
+	byDashboard := make(map[int64]*m.DashboardWithSilenceInfo)
+	for _, item := range res {
+		dash, exists := byDashboard[item.DashboardId]
+		if !exists {
+			dash = &m.DashboardWithSilenceInfo{
+				DashboardId: item.DashboardId,
+				Title:       item.Title,
+			}
+			byDashboard[item.DashboardId] = dash
+			query.Result = append(query.Result, dash)
+		}
+
+		if item.SilencedUntil.After(dash.SilencedUntil) {
+			dash.SilencedUntil = item.SilencedUntil
+		}
+	}
+
+	return nil
+}
+
+func GetDashboardsByAnnotationTag(query *m.GetDashboardsByAnnotationTagQuery) error {
+	query.Result = make([]*m.Dashboard, 0)
+
+	tags := m.ParseTagPairs([]string{query.Tag})
+	if len(tags) == 0 {
+		return nil
+	}
+
+	var sql bytes.Buffer
+	params := []interface{}{query.OrgId, query.OrgId, tags[0].Key}
+
+	sql.WriteString(`org_id=? AND id IN (
+					SELECT annotation.dashboard_id
+					FROM annotation
+					INNER JOIN annotation_tag on annotation_tag.annotation_id = annotation.id
+					INNER JOIN tag on tag.id = annotation_tag.tag_id
+					WHERE annotation.org_id=? AND tag.key=?`)
+
+	if tags[0].Value != "" {
+		sql.WriteString(` AND tag.value=?`)
+		params = append(params, tags[0].Value)
+	}
+
+	sql.WriteString(`)`)
+
+	return x.Where(sql.String(), params...).Asc("title").Find(&query.Result)
+}
+
+func GetDashboardsByQueryTimeout(query *m.GetDashboardsByQueryTimeoutQuery) error {
+	minTimeout := time.Duration(query.MinTimeoutSeconds) * time.Second
+	query.Result = make([]*m.Dashboard, 0)
+
+	where, params := jsonLike(`"timeout":`)
+
+	return forEachDashboard(query.OrgId, where, params, func(dash *m.Dashboard) {
+		if timeout := dash.GetMaxQueryTimeout(); timeout > 0 && timeout >= minTimeout {
+			query.Result = append(query.Result, dash)
+		}
+	})
+}
+
+func GetDashboardsByTemplateVariableCount(query *m.GetDashboardsByTemplateVariableCountQuery) error {
+	query.Result = make([]*m.DashboardWithVariableCount, 0)
+
+	where, params := "", []interface{}{}
+	if query.MinVariables > 0 {
+		where, params = jsonLike(`"list":[{`)
+	}
+
+	err := forEachDashboard(query.OrgId, where, params, func(dash *m.Dashboard) {
+		count := len(dash.GetTemplateVariables())
+		if count >= query.MinVariables {
+			query.Result = append(query.Result, &m.DashboardWithVariableCount{
+				DashboardId:   dash.Id,
+				Title:         dash.Title,
+				Slug:          dash.Slug,
+				VariableCount: count,
+			})
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(query.Result, func(i, j int) bool {
+		return query.Result[i].VariableCount > query.Result[j].VariableCount
+	})
+
+	return nil
+}
+
+func GetDashboardsByAlertFrequency(query *m.GetDashboardsByAlertFrequencyQuery) error {
+	var rawSql = `SELECT
+					dashboard.id as dashboard_id,
+					dashboard.title,
+					dashboard.slug,
+					MIN(alert.frequency) as min_frequency
+					FROM dashboard
+					INNER JOIN alert on alert.dashboard_id = dashboard.id
+					WHERE dashboard.org_id=?
+					GROUP BY dashboard.id, dashboard.title, dashboard.slug
+					HAVING MIN(alert.frequency) <= ?
+					ORDER BY min_frequency ASC, dashboard.title ASC`
+
+	query.Result = make([]*m.DashboardWithAlertFrequency, 0)
+	return x.Sql(rawSql, query.OrgId, query.MaxFrequencySeconds).Find(&query.Result)
+}
+
+func GetDashboardsByLastAnnotation(query *m.GetDashboardsByLastAnnotationQuery) error {
+	var rawSql = `SELECT
+					dashboard.id as dashboard_id,
+					dashboard.title,
+					dashboard.slug,
+					MAX(annotation.epoch) as last_annotation_epoch
+					FROM dashboard
+					INNER JOIN annotation on annotation.dashboard_id = dashboard.id
+					WHERE dashboard.org_id=? AND annotation.epoch >= ?
+					GROUP BY dashboard.id, dashboard.title, dashboard.slug
+					ORDER BY last_annotation_epoch DESC`
+
+	query.Result = make([]*m.DashboardWithAnnotationInfo, 0)
+	return x.Sql(rawSql, query.OrgId, query.Since.Unix()).Find(&query.Result)
+}
+
+func GetDashboardsByAnnotationCount(query *m.GetDashboardsByAnnotationCountQuery) error {
+	var rawSql = `SELECT
+					dashboard.id as dashboard_id,
+					dashboard.title,
+					dashboard.slug,
+					COUNT(annotation.id) as annotation_count
+					FROM dashboard
+					LEFT JOIN annotation on annotation.dashboard_id = dashboard.id
+					WHERE dashboard.org_id=?
+					GROUP BY dashboard.id, dashboard.title, dashboard.slug
+					HAVING COUNT(annotation.id) >= ?
+					ORDER BY annotation_count DESC, dashboard.title ASC`
+
+	query.Result = make([]*m.DashboardWithAnnotationCount, 0)
+	return x.Sql(rawSql, query.OrgId, query.MinAnnotations).Find(&query.Result)
+}
+
+func GetDashboardsByHasAnnotations(query *m.GetDashboardsByHasAnnotationsQuery) error {
+	filter := "EXISTS"
+	if !query.HasAnnotations {
+		filter = "NOT EXISTS"
+	}
+
+	var dashboards = make([]*m.Dashboard, 0)
+	err := x.Where("org_id=? AND "+filter+" (SELECT 1 FROM annotation WHERE annotation.dashboard_id = dashboard.id)", query.OrgId).
+		Asc("title").
+		Find(&dashboards)
+
+	query.Result = dashboards
+	return err
+}
+
+// GetDashboardsByOrgWithAnnotationSummary returns a page of the org dashboards
+// together with the number of annotations added since query.Since.
+func GetDashboardsByOrgWithAnnotationSummary(query *m.GetDashboardsByOrgWithAnnotationSummaryQuery) error {
+	var sql bytes.Buffer
+	params := []interface{}{query.Since.Unix(), query.OrgId}
+
+	sql.WriteString(`SELECT
+					dashboard.id as dashboard_id,
+					dashboard.title,
+					dashboard.slug,
+					COUNT(annotation.id) as annotation_count
+					FROM dashboard
+					LEFT JOIN annotation on annotation.dashboard_id = dashboard.id AND annotation.epoch >= ?
+					WHERE dashboard.org_id=?
+					GROUP BY dashboard.id, dashboard.title, dashboard.slug
+					ORDER BY dashboard.title ASC`)
+
+	if query.Limit > 0 {
+		if query.Page < 1 {
+			query.Page = 1
+		}
+		sql.WriteString(" LIMIT ? OFFSET ?")
+		params = append(params, query.Limit, query.Limit*(query.Page-1))
+	}
+
+	query.Result = make([]*m.DashboardWithAnnotationCount, 0)
+	return x.Sql(sql.String(), params...).Find(&query.Result)
+}
+
+func GetDashboardsByHasPluginId(query *m.GetDashboardsByHasPluginIdQuery) error {
+	filter := "(plugin_id = '' OR plugin_id IS NULL)"
+	if query.HasPluginId {
+		filter = "(plugin_id <> '' AND plugin_id IS NOT NULL)"
+	}
+
+	var dashboards = make([]*m.Dashboard, 0)
+	err := x.Where("org_id=? AND "+filter, query.OrgId).Asc("title").Find(&dashboards)
+
+	query.Result = dashboards
+	return err
+}
+
+func GetDashboardsByOrgWithUserInfo(query *m.GetDashboardsByOrgWithUserInfoQuery) error {
+	var sql bytes.Buffer
+	params := []interface{}{query.OrgId}
+
+	sql.WriteString(`SELECT
+					dashboard.id as dashboard_id,
+					dashboard.title,
+					dashboard.slug,
+					dashboard.created,
+					dashboard.updated,
+					creator.login as created_by_login,
+					creator.email as created_by_email,
+					updater.login as updated_by_login,
+					updater.email as updated_by_email
+					FROM dashboard
+					LEFT OUTER JOIN ` + dialect.Quote("user") + ` creator on creator.id = dashboard.created_by
+					LEFT OUTER JOIN ` + dialect.Quote("user") + ` updater on updater.id = dashboard.updated_by
+					WHERE dashboard.org_id=?
+					ORDER BY dashboard.title ASC`)
+
+	if query.Limit > 0 {
+		if query.Page < 1 {
+			query.Page = 1
+		}
+		sql.WriteString(" LIMIT ? OFFSET ?")
+		params = append(params, query.Limit, query.Limit*(query.Page-1))
+	}
+
+	query.Result = make([]*m.DashboardWithUserInfo, 0)
+	return x.Sql(sql.String(), params...).Find(&query.Result)
+}
+
+func GetDashboardsByStarredAndTag(query *m.GetDashboardsByStarredAndTagQuery) error {
+	var rawSql = `SELECT dashboard.* FROM dashboard
+					INNER JOIN star on star.dashboard_id = dashboard.id AND star.user_id = ?
+					INNER JOIN dashboard_tag on dashboard_tag.dashboard_id = dashboard.id AND dashboard_tag.term = ?
+					WHERE dashboard.org_id=?
+					ORDER BY dashboard.title ASC`
+
+	query.Result = make([]*m.Dashboard, 0)
+	return x.Sql(rawSql, query.UserId, query.Tag, query.OrgId).Find(&query.Result)
+}
+
+// GetDashboardsByOrgAndMaxSize returns the dashboards whose json is at most
+// MaxBytes long, largest first.
+func GetDashboardsByOrgAndMaxSize(query *m.GetDashboardsByOrgAndMaxSizeQuery) error {
+	var rawSql = `SELECT
+					dashboard.id as dashboard_id,
+					dashboard.title,
+					dashboard.slug,
+					` + dashboardDataSizeSql() + ` as size
+					FROM dashboard
+					WHERE dashboard.org_id=? AND ` + dashboardDataSizeSql() + ` <= ?
+					ORDER BY size DESC, dashboard.title ASC`
+
+	query.Result = make([]*m.DashboardWithSize, 0)
+	return x.Sql(rawSql, query.OrgId, query.MaxBytes).Find(&query.Result)
+}
+
+// dashboardDataSizeSql returns the size of the dashboard json in bytes,
+// LENGTH counts characters on postgres and for sqlite text values
+func dashboardDataSizeSql() string {
+	switch dialect.DriverName() {
+	case migrator.POSTGRES, migrator.MYSQL:
+		return "OCTET_LENGTH(dashboard.data)"
+	default:
+		return "LENGTH(CAST(dashboard.data AS BLOB))"
+	}
+}
+
+func GetDashboardsByOrgExcludingPluginDashboards(query *m.GetDashboardsByOrgExcludingPluginDashboardsQuery) error {
+	var dashboards = make([]*m.Dashboard, 0)
+
+	sess := x.Where("org_id=? AND (plugin_id = '' OR plugin_id IS NULL)", query.OrgId).Asc("title")
+	if query.Limit > 0 {
+		if query.Page < 1 {
+			query.Page = 1
+		}
+		sess.Limit(query.Limit, query.Limit*(query.Page-1))
+	}
+
+	err := sess.Find(&dashboards)
+	query.Result = dashboards
+	return err
+}
+
+// GetDashboardsByOrgAndUpdatedBeforeDate returns a page of the dashboards that
+// have not been updated since the given date, least recently updated first.
+func GetDashboardsByOrgAndUpdatedBeforeDate(query *m.GetDashboardsByOrgAndUpdatedBeforeDateQuery) error {
+	var dashboards = make([]*m.Dashboard, 0)
+
+	sess := x.Where("org_id=? AND updated < ?", query.OrgId, query.UpdatedBefore).Asc("updated", "title")
+	if query.Limit > 0 {
+		if query.Page < 1 {
+			query.Page = 1
+		}
+		sess.Limit(query.Limit, query.Limit*(query.Page-1))
+	}
+
+	err := sess.Find(&dashboards)
+	query.Result = dashboards
+	return err
+}
+
+func GetDashboardsByOrgWithTagSummary(query *m.GetDashboardsByOrgWithTagSummaryQuery) error {
+	query.Result = make([]*m.DashboardWithTagSummary, 0)
+
+	// page on dashboards first, the tag join multiplies the rows
+	var page []DashboardIdDTO
+	sess := x.Table("dashboard").Cols("id").Where("org_id=?", query.OrgId).Asc("title")
+	if query.Limit > 0 {
+		if query.Page < 1 {
+			query.Page = 1
+		}
+		sess.Limit(query.Limit, query.Limit*(query.Page-1))
+	}
+
+	if err := sess.Find(&page); err != nil {
+		return err
+	}
+
+	if len(page) == 0 {
+		return nil
+	}
+
+	params := make([]interface{}, 0)
+	for _, item := range page {
+		params = append(params, item.Id)
+	}
+
+	rawSql := `SELECT
+					dashboard.id,
+					dashboard.title,
+					dashboard.slug,
+					dashboard_tag.term
+					FROM dashboard
+					LEFT OUTER JOIN dashboard_tag on dashboard_tag.dashboard_id = dashboard.id
+					WHERE dashboard.id IN (?` + strings.Repeat(",?", len(params)-1) + `)
+					ORDER BY dashboard.title ASC, dashboard_tag.term ASC`
+
+	var res []DashboardSearchProjection
+	if err := x.Sql(rawSql, params...).Find(&res); err != nil {
+		return err
+	}
+
+	summaries := make(map[int64]*m.DashboardWithTagSummary)
+	for _, item := range res {
+		summary, exists := summaries[item.Id]
+		if !exists {
+			summary = &m.DashboardWithTagSummary{
+				DashboardId: item.Id,
+				Title:       item.Title,
+				Slug:        item.Slug,
+				Tags:        []string{},
+			}
+			summaries[item.Id] = summary
+			query.Result = append(query.Result, summary)
+		}
+		if len(item.Term) > 0 {
+			summary.Tags = append(summary.Tags, item.Term)
+		}
+	}
+
+	return nil
+}
+
+// GetDashboardsByNonExistentDatasource returns the dashboards with panels
+// referring to datasources that are not in the org anymore. Built-in
+// datasources and template variables are not checked.
+func GetDashboardsByNonExistentDatasource(query *m.GetDashboardsByNonExistentDatasourceQuery) error {
+	datasources := make([]*m.DataSource, 0)
+	if err := x.Where("org_id=?", query.OrgId).Find(&datasources); err != nil {
+		return err
+	}
+
+	existing := map[string]bool{
+		"-- Grafana --": true,
+		"-- Mixed --":   true,
+	}
+	for _, ds := range datasources {
+		existing[ds.Name] = true
+		if ds.IsDefault {
+			existing[""] = true
+		}
+	}
+
+	// without a default datasource, panels that don't name one are broken too
+	where, params := "", []interface{}{}
+	if existing[""] {
+		where, params = jsonLike(`"datasource":"`)
+	}
+
+	query.Result = make([]*m.DashboardWithBrokenDatasource, 0)
+
+	return forEachDashboard(query.OrgId, where, params, func(dash *m.Dashboard) {
+		missing := make([]string, 0)
+		for _, name := range dash.GetDatasourceRefs() {
+			if !existing[name] && !strings.HasPrefix(name, "$") {
+				missing = append(missing, name)
+			}
+		}
+
+		if len(missing) > 0 {
+			query.Result = append(query.Result, &m.DashboardWithBrokenDatasource{
+				DashboardId:        dash.Id,
+				Title:              dash.Title,
+				Slug:               dash.Slug,
+				MissingDatasources: missing,
+			})
+		}
+	})
+}
+
+// GetDashboardsByVersionMessageKeyword searches the version messages of all
+// dashboards in the org, matching is case insensitive on all dialects.
+func GetDashboardsByVersionMessageKeyword(query *m.GetDashboardsByVersionMessageKeywordQuery) error {
+	var rawSql = `SELECT
+					dashboard.id as dashboard_id,
+					dashboard.title as dashboard_title,
+					dashboard_version.version,
+					dashboard_version.message
+					FROM dashboard
+					INNER JOIN dashboard_version on dashboard_version.dashboard_id = dashboard.id
+					WHERE dashboard.org_id=? AND dashboard_version.message ` + dialect.LikeStr() + ` ?
+					ORDER BY dashboard.title ASC, dashboard_version.version DESC`
+
+	query.Result = make([]*m.DashboardVersionMatch, 0)
+	return x.Sql(rawSql, query.OrgId, "%"+query.Keyword+"%").Find(&query.Result)
+}
+
+func GetDashboardsByOrgWithAlertStatus(query *m.GetDashboardsByOrgWithAlertStatusQuery) error {
+	var rawSql = `SELECT
+					dashboard.id as dashboard_id,
+					dashboard.title,
+					SUM(CASE WHEN alert.state = ? THEN 1 ELSE 0 END) as alerting_count,
+					SUM(CASE WHEN alert.state = ? THEN 1 ELSE 0 END) as ok_count,
+					SUM(CASE WHEN alert.state = ? THEN 1 ELSE 0 END) as no_data_count,
+					SUM(CASE WHEN alert.state = ? THEN 1 ELSE 0 END) as pending_count
+					FROM dashboard
+					INNER JOIN alert on alert.dashboard_id = dashboard.id
+					WHERE dashboard.org_id=?
+					GROUP BY dashboard.id, dashboard.title
+					ORDER BY dashboard.title ASC`
+
+	params := []interface{}{
+		string(m.AlertStateAlerting),
+		string(m.AlertStateOK),
+		string(m.AlertStateNoData),
+		string(m.AlertStatePending),
+		query.OrgId,
+	}
+
+	query.Result = make([]*m.DashboardWithAlertStatus, 0)
+	return x.Sql(rawSql, params...).Find(&query.Result)
+}
+
+func GetDashboardsByPanelCount(query *m.GetDashboardsByPanelCountQuery) error {
+	query.Result = make([]*m.DashboardWithPanelCount, 0)
+
+	where, params := "", []interface{}{}
+	if query.MinPanels > 0 {
+		where, params = jsonLike(`"panels":[{`)
+	}
+
+	err := forEachDashboard(query.OrgId, where, params, func(dash *m.Dashboard) {
+		count := 0
+		for _, panel := range dash.GetPanels() {
+			if panel.Get("type").MustString() != "row" {
+				count++
+			}
+		}
+
+		if count < query.MinPanels || (query.MaxPanels > 0 && count > query.MaxPanels) {
+			return
+		}
+
+		query.Result = append(query.Result, &m.DashboardWithPanelCount{
+			DashboardId: dash.Id,
+			Title:       dash.Title,
+			Slug:        dash.Slug,
+			PanelCount:  count,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(query.Result, func(i, j int) bool {
+		return query.Result[i].PanelCount > query.Result[j].PanelCount
+	})
+
+	return nil
+}
+
+// GetDashboardsByQueryTargetCount returns the dashboards where the total
+// number of query targets over all panels is at least MinTargets.
+func GetDashboardsByQueryTargetCount(query *m.GetDashboardsByQueryTargetCountQuery) error {
+	query.Result = make([]*m.DashboardWithTargetCount, 0)
+
+	where, params := "", []interface{}{}
+	if query.MinTargets > 0 {
+		where, params = jsonLike(`"targets":[{`)
+	}
+
+	err := forEachDashboard(query.OrgId, where, params, func(dash *m.Dashboard) {
+		total, max := countQueryTargets(dash)
+		if total < query.MinTargets {
+			return
+		}
+
+		query.Result = append(query.Result, &m.DashboardWithTargetCount{
+			DashboardId:    dash.Id,
+			Title:          dash.Title,
+			Slug:           dash.Slug,
+			TargetCount:    total,
+			MaxPanelTarget: max,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(query.Result, func(i, j int) bool {
+		return query.Result[i].TargetCount > query.Result[j].TargetCount
+	})
+
+	return nil
+}
+
+// GetDashboardsByRowCount returns the dashboards with at least MinRows rows,
+// both legacy rows and row panels are counted.
+func GetDashboardsByRowCount(query *m.GetDashboardsByRowCountQuery) error {
+	query.Result = make([]*m.DashboardWithRowCount, 0)
+
+	where, params := "", []interface{}{}
+	if query.MinRows > 0 {
+		where, params = jsonLike(`"rows":[{`, `"type":"row"`)
+	}
+
+	err := forEachDashboard(query.OrgId, where, params, func(dash *m.Dashboard) {
+		count := len(dash.Data.Get("rows").MustArray())
+		for _, panel := range dash.GetPanels() {
+			if panel.Get("type").MustString() == "row" {
+				count++
+			}
+		}
+
+		if count < query.MinRows {
+			return
+		}
+
+		query.Result = append(query.Result, &m.DashboardWithRowCount{
+			DashboardId: dash.Id,
+			Title:       dash.Title,
+			Slug:        dash.Slug,
+			RowCount:    count,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(query.Result, func(i, j int) bool {
+		return query.Result[i].RowCount > query.Result[j].RowCount
+	})
+
+	return nil
+}
+
+// GetDashboardsByVersionRestoreCount returns the dashboards that have been
+// restored from an older version at least MinRestores times, dashboards that
+// were never restored are not returned.
+func GetDashboardsByVersionRestoreCount(query *m.GetDashboardsByVersionRestoreCountQuery) error {
+	var rawSql = `SELECT
+					dashboard.id as dashboard_id,
+					dashboard.title,
+					dashboard.slug,
+					COUNT(*) as restore_count
+					FROM dashboard
+					INNER JOIN dashboard_version on dashboard_version.dashboard_id = dashboard.id
+					WHERE dashboard.org_id=? AND dashboard_version.restored_from > 0
+					GROUP BY dashboard.id, dashboard.title, dashboard.slug
+					HAVING COUNT(*) >= ?
+					ORDER BY restore_count DESC, dashboard.title ASC`
+
+	query.Result = make([]*m.DashboardWithRestoreCount, 0)
+	return x.Sql(rawSql, query.OrgId, query.MinRestores).Find(&query.Result)
+}
+
+// GetDashboardsByOrgWithFolderAndTagSummary returns a page of the org
+// dashboards with their tags, number of saved versions and number of stars.
+// The page and the related rows are read with separate queries in one
+// transaction and joined here. This tree has no folders, so there is no
+// folder title or slug.
+func GetDashboardsByOrgWithFolderAndTagSummary(query *m.GetDashboardsByOrgWithFolderAndTagSummaryQuery) error {
+	return inTransaction(func(sess *DBSession) error {
+		query.Result = make([]*m.DashboardFullSummary, 0)
+
+		var dashboards = make([]*m.Dashboard, 0)
+		sess.Table("dashboard").Cols("id", "title", "slug", "version", "updated").Where("org_id=?", query.OrgId).Asc("title")
+		if query.Limit > 0 {
+			if query.Page < 1 {
+				query.Page = 1
+			}
+			sess.Limit(query.Limit, query.Limit*(query.Page-1))
+		}
+
+		if err := sess.Find(&dashboards); err != nil {
+			return err
+		}
+
+		if len(dashboards) == 0 {
+			return nil
+		}
+
+		summaries := make(map[int64]*m.DashboardFullSummary)
+		params := make([]interface{}, 0)
+		for _, dash := range dashboards {
+			summary := &m.DashboardFullSummary{
+				DashboardId: dash.Id,
+				Title:       dash.Title,
+				Slug:        dash.Slug,
+				Version:     dash.Version,
+				Updated:     dash.Updated,
+				Tags:        []string{},
+			}
+			summaries[dash.Id] = summary
+			query.Result = append(query.Result, summary)
+			params = append(params, dash.Id)
+		}
+
+		inIds := "(?" + strings.Repeat(",?", len(params)-1) + ")"
+
+		var tags []DashboardTag
+		if err := sess.Sql("SELECT dashboard_id, term FROM dashboard_tag WHERE dashboard_id IN "+inIds+" ORDER BY term ASC", params...).Find(&tags); err != nil {
+			return err
+		}
+
+		for _, tag := range tags {
+			summaries[tag.DashboardId].Tags = append(summaries[tag.DashboardId].Tags, tag.Term)
+		}
+
+		var versionCounts []DashboardCountProjection
+		if err := sess.Sql("SELECT dashboard_id, COUNT(*) as count FROM dashboard_version WHERE dashboard_id IN "+inIds+" GROUP BY dashboard_id", params...).Find(&versionCounts); err != nil {
+			return err
+		}
+
+		for _, item := range versionCounts {
+			summaries[item.DashboardId].VersionCount = item.Count
+		}
+
+		var starCounts []DashboardCountProjection
+		if err := sess.Sql("SELECT dashboard_id, COUNT(*) as count FROM star WHERE dashboard_id IN "+inIds+" GROUP BY dashboard_id", params...).Find(&starCounts); err != nil {
+			return err
+		}
+
+		for _, item := range starCounts {
+			summaries[item.DashboardId].StarCount = item.Count
+		}
+
+		return nil
+	})
+}
+
+func GetDashboardsByNoDataAlertCount(query *m.GetDashboardsByNoDataAlertCountQuery) error {
+	var rawSql = `SELECT
+					dashboard.id as dashboard_id,
+					dashboard.title,
+					dashboard.slug,
+					SUM(CASE WHEN alert.state = ? THEN 1 ELSE 0 END) as no_data_count
+					FROM dashboard
+					INNER JOIN alert on alert.dashboard_id = dashboard.id
+					WHERE dashboard.org_id=?
+					GROUP BY dashboard.id, dashboard.title, dashboard.slug
+					HAVING SUM(CASE WHEN alert.state = ? THEN 1 ELSE 0 END) >= ?
+					ORDER BY no_data_count DESC, dashboard.title ASC`
+
+	params := []interface{}{string(m.AlertStateNoData), query.OrgId, string(m.AlertStateNoData), query.MinNoDataAlerts}
+
+	query.Result = make([]*m.DashboardWithNoDataCount, 0)
+	return x.Sql(rawSql, params...).Find(&query.Result)
+}
+
+func GetDashboardsByQueryCountRange(query *m.GetDashboardsByQueryCountRangeQuery) error {
+	query.Result = make([]*m.DashboardWithQueryCount, 0)
+
+	where, params := "", []interface{}{}
+	if query.MinQueries > 0 {
+		where, params = jsonLike(`"targets":[{`)
+	}
+
+	return forEachDashboard(query.OrgId, where, params, func(dash *m.Dashboard) {
+		count, _ := countQueryTargets(dash)
+		if count < query.MinQueries || (query.MaxQueries > 0 && count > query.MaxQueries) {
+			return
+		}
+
+		query.Result = append(query.Result, &m.DashboardWithQueryCount{
+			DashboardId: dash.Id,
+			Title:       dash.Title,
+			Slug:        dash.Slug,
+			QueryCount:  count,
+		})
+	})
+}
+
+// dashboardBatchSize is the number of dashboards loaded at once by queries
+// that have to inspect the dashboard json in Go
+var dashboardBatchSize = 100
+
+// forEachDashboard calls fn for every dashboard of the org matching the
+// optional where condition, ordered by title. Dashboards are loaded a batch
+// at a time so the json of the whole org is never held in memory at once.
+func forEachDashboard(orgId int64, where string, params []interface{}, fn func(*m.Dashboard)) error {
+	condition := "org_id=?"
+	args := append([]interface{}{orgId}, params...)
+	if where != "" {
+		condition += " AND " + where
+	}
+
+	for offset := 0; ; offset += dashboardBatchSize {
+		dashboards := make([]*m.Dashboard, 0)
+		err := x.Where(condition, args...).Asc("title", "id").Limit(dashboardBatchSize, offset).Find(&dashboards)
+		if err != nil {
+			return err
+		}
+
+		for _, dash := range dashboards {
+			fn(dash)
+		}
+
+		if len(dashboards) < dashboardBatchSize {
+			return nil
+		}
+	}
+}
+
+// jsonLike returns a condition matching dashboards whose json contains any of
+// the fragments. Dashboards are stored as compact json, so the condition only
+// narrows down the dashboards that have to be inspected. The LIKE escape
+// character differs between databases, so no condition is returned when a
+// fragment contains a backslash.
+func jsonLike(fragments ...string) (string, []interface{}) {
+	conditions := make([]string, 0)
+	params := make([]interface{}, 0)
+
+	for _, fragment := range fragments {
+		if strings.Contains(fragment, `\`) {
+			return "", []interface{}{}
+		}
+
+		conditions = append(conditions, "data "+dialect.LikeStr()+" ?")
+		params = append(params, "%"+fragment+"%")
+	}
+
+	if len(conditions) == 0 {
+		return "", params
+	}
+
+	return "(" + strings.Join(conditions, " OR ") + ")", params
+}
+
+// jsonKeyValue returns "key":"value" the way it appears in the stored json
+func jsonKeyValue(key string, value string) string {
+	encoded, _ := json.Marshal(value)
+	return `"` + key + `":` + string(encoded)
+}
+
+// countQueryTargets returns the number of query targets over all panels
+// and the largest number of targets on a single panel
+func countQueryTargets(dash *m.Dashboard) (int, int) {
+	total, max := 0, 0
+	for _, panel := range dash.GetPanels() {
+		count := len(panel.Get("targets").MustArray())
+		total += count
+		if count > max {
+			max = count
+		}
+	}
+
+	return total, max
+}
+
+// GetDashboardsByAlertPendingDuration returns the dashboards with alerts that
+// went back to pending and have been stuck there for longer than the given
+// duration. Alerts that were never evaluated are not included.
+func GetDashboardsByAlertPendingDuration(query *m.GetDashboardsByAlertPendingDurationQuery) error {
+	var rawSql = `SELECT
+					dashboard.id as dashboard_id,
+					dashboard.title,
+					dashboard.slug,
+					COUNT(alert.id) as pending_count
+					FROM dashboard
+					INNER JOIN alert on alert.dashboard_id = dashboard.id
+					WHERE dashboard.org_id=? AND alert.state = ? AND alert.state_changes >= 1 AND alert.new_state_date <= ?
+					GROUP BY dashboard.id, dashboard.title, dashboard.slug
+					ORDER BY pending_count DESC, dashboard.title ASC`
+
+	pendingSince := time.Now().Add(-query.PendingForMoreThan)
+
+	query.Result = make([]*m.DashboardWithPendingAlerts, 0)
+	return x.Sql(rawSql, query.OrgId, string(m.AlertStatePending), pendingSince).Find(&query.Result)
+}
+
+type DashboardAlertTransitionProjection struct {
+	DashboardId  int64
+	Title        string
+	Slug         string
+	NewStateDate time.Time
+}
+
+// GetDashboardsByOrgWithRecentAlertTransitions returns the dashboards with
+// alerts that changed state since query.Since, most recent change first. Only
+// the latest transition of each alert is stored, so TransitionCount is the
+// number of alerts that changed state.
+func GetDashboardsByOrgWithRecentAlertTransitions(query *m.GetDashboardsByOrgWithRecentAlertTransitionsQuery) error {
+	// aggregated dates are not typed consistently by the drivers,
+	// so the latest transition is picked in Go
+	var rawSql = `SELECT
+					dashboard.id as dashboard_id,
+					dashboard.title,
+					dashboard.slug,
+					alert.new_state_date
+					FROM dashboard
+					INNER JOIN alert on alert.dashboard_id = dashboard.id
+					WHERE dashboard.org_id=? AND alert.new_state_date >= ?`
+
+	var res []DashboardAlertTransitionProjection
+	if err := x.Sql(rawSql, query.OrgId, query.Since).Find(&res); err != nil {
+		return err
+	}
+
+	query.Result = make([]*m.DashboardWithAlertTransitions, 0)
+	byDashboard := make(map[int64]*m.DashboardWithAlertTransitions)
+	for _, item := range res {
+		dash, exists := byDashboard[item.DashboardId]
+		if !exists {
+			dash = &m.DashboardWithAlertTransitions{
+				DashboardId: item.DashboardId,
+				Title:       item.Title,
+				Slug:        item.Slug,
+			}
+			byDashboard[item.DashboardId] = dash
+			query.Result = append(query.Result, dash)
+		}
+
+		dash.TransitionCount++
+		if item.NewStateDate.After(dash.LastTransitionAt) {
+			dash.LastTransitionAt = item.NewStateDate
+		}
+	}
+
+	sort.SliceStable(query.Result, func(i, j int) bool {
+		return query.Result[i].LastTransitionAt.After(query.Result[j].LastTransitionAt)
+	})
+
+	return nil
+}
+
 type DashboardSlugDTO struct {
 	Slug string
 }
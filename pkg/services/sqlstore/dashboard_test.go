@@ -1,13 +1,19 @@
 package sqlstore
 
 import (
+	"context"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 
 	"github.com/gosimple/slug"
+	"github.com/grafana/grafana/pkg/bus"
 	"github.com/grafana/grafana/pkg/components/simplejson"
 	m "github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/annotations"
 	"github.com/grafana/grafana/pkg/services/search"
 )
 
@@ -27,6 +33,71 @@ func insertTestDashboard(title string, orgId int64, tags ...interface{}) *m.Dash
 	return cmd.Result
 }
 
+func insertTestDashboardWithData(title string, orgId int64, data map[string]interface{}) *m.Dashboard {
+	data["id"] = nil
+	data["title"] = title
+
+	cmd := m.SaveDashboardCommand{
+		OrgId:     orgId,
+		Dashboard: simplejson.NewFromAny(data),
+	}
+
+	err := SaveDashboard(&cmd)
+	So(err, ShouldBeNil)
+
+	return cmd.Result
+}
+
+func saveTestDashboardVersion(dash *m.Dashboard, message string) *m.Dashboard {
+	cmd := m.SaveDashboardCommand{
+		OrgId:     dash.OrgId,
+		Overwrite: true,
+		Message:   message,
+		Dashboard: simplejson.NewFromAny(map[string]interface{}{
+			"id":    dash.Id,
+			"title": dash.Title,
+			"tags":  dash.Data.Get("tags").MustArray(),
+		}),
+	}
+
+	err := SaveDashboard(&cmd)
+	So(err, ShouldBeNil)
+
+	return cmd.Result
+}
+
+func insertTestAlerts(dash *m.Dashboard, alerts ...*m.Alert) {
+	for _, alert := range alerts {
+		alert.DashboardId = dash.Id
+		alert.OrgId = dash.OrgId
+		if alert.Settings == nil {
+			alert.Settings = simplejson.New()
+		}
+	}
+
+	err := SaveAlerts(&m.SaveAlertsCommand{
+		DashboardId: dash.Id,
+		OrgId:       dash.OrgId,
+		Alerts:      alerts,
+	})
+	So(err, ShouldBeNil)
+}
+
+func setTestAlertStates(dash *m.Dashboard, states ...m.AlertStateType) {
+	query := m.GetAlertsQuery{OrgId: dash.OrgId, DashboardId: dash.Id}
+	So(HandleAlertsQuery(&query), ShouldBeNil)
+
+	// alerts are ordered by name
+	for i, state := range states {
+		if state == m.AlertStatePending {
+			continue
+		}
+
+		err := SetAlertState(&m.SetAlertStateCommand{AlertId: query.Result[i].Id, OrgId: dash.OrgId, State: state})
+		So(err, ShouldBeNil)
+	}
+}
+
 func TestDashboardDataAccess(t *testing.T) {
 
 	Convey("Testing DB", t, func() {
@@ -56,6 +127,47 @@ func TestDashboardDataAccess(t *testing.T) {
 				So(query.Result.Slug, ShouldEqual, "test-dash-23")
 			})
 
+			Convey("Should be able to get dashboard by title", func() {
+				query := m.GetDashboardQuery{
+					Title: "test dash 23",
+					OrgId: 1,
+				}
+
+				err := GetDashboard(&query)
+				So(err, ShouldBeNil)
+
+				So(query.Result.Id, ShouldEqual, savedDash.Id)
+				So(query.Result.Data.Get("id").MustInt64(), ShouldEqual, savedDash.Id)
+			})
+
+			Convey("Should not find dashboard by title in another org", func() {
+				query := m.GetDashboardQuery{
+					Title: "test dash 23",
+					OrgId: 2,
+				}
+
+				err := GetDashboard(&query)
+				So(err, ShouldEqual, m.ErrDashboardNotFound)
+			})
+
+			Convey("Should refuse ambiguous title lookups", func() {
+				dup := m.NewDashboard("test dash 23")
+				dup.OrgId = 1
+				dup.Slug = "test-dash-23-copy"
+				dup.Created = time.Now()
+				dup.Updated = time.Now()
+				_, err := x.Insert(dup)
+				So(err, ShouldBeNil)
+
+				query := m.GetDashboardQuery{
+					Title: "test dash 23",
+					OrgId: 1,
+				}
+
+				err = GetDashboard(&query)
+				So(err, ShouldEqual, m.ErrDashboardTitleNotUnique)
+			})
+
 			Convey("Should be able to delete dashboard", func() {
 				insertTestDashboard("delete me", 1, "delete this")
 
@@ -69,6 +181,79 @@ func TestDashboardDataAccess(t *testing.T) {
 				So(err, ShouldBeNil)
 			})
 
+			Convey("Should not delete dashboard when its alerts cannot be deleted", func() {
+				_, err := x.Exec("DROP TABLE alert")
+				So(err, ShouldBeNil)
+
+				err = DeleteDashboard(&m.DeleteDashboardCommand{
+					Slug:  savedDash.Slug,
+					OrgId: 1,
+				})
+				So(err, ShouldNotBeNil)
+
+				query := m.GetDashboardQuery{Slug: savedDash.Slug, OrgId: 1}
+				err = GetDashboard(&query)
+				So(err, ShouldBeNil)
+				So(query.Result.Id, ShouldEqual, savedDash.Id)
+			})
+
+			Convey("Should be able to delete several dashboards at once", func() {
+				secondDash := insertTestDashboard("test dash 89", 1, "prod")
+				insertTestAlerts(secondDash, &m.Alert{Name: "alert 1"})
+				updateTestDashboard(secondDash, map[string]interface{}{"tags": []interface{}{"prod"}})
+
+				err := StarDashboard(&m.StarDashboardCommand{UserId: 10, DashboardId: savedDash.Id})
+				So(err, ShouldBeNil)
+
+				err = CreatePlaylist(&m.CreatePlaylistCommand{
+					OrgId: 1,
+					Name:  "cleanup",
+					Items: []m.PlaylistItemDTO{
+						{Type: "dashboard_by_id", Value: fmt.Sprint(savedDash.Id)},
+					},
+				})
+				So(err, ShouldBeNil)
+
+				err = DeleteDashboards(&m.DeleteDashboardsCommand{
+					DashboardIds: []int64{savedDash.Id, secondDash.Id},
+					OrgId:        1,
+				})
+				So(err, ShouldBeNil)
+
+				for _, id := range []int64{savedDash.Id, secondDash.Id} {
+					query := m.GetDashboardQuery{Id: id, OrgId: 1}
+					So(GetDashboard(&query), ShouldEqual, m.ErrDashboardNotFound)
+
+					tags, _ := x.Where("dashboard_id=?", id).Count(&DashboardTag{})
+					So(tags, ShouldEqual, 0)
+					versions, _ := x.Where("dashboard_id=?", id).Count(&m.DashboardVersion{})
+					So(versions, ShouldEqual, 0)
+					alerts, _ := x.Where("dashboard_id=?", id).Count(&m.Alert{})
+					So(alerts, ShouldEqual, 0)
+				}
+
+				stars, _ := x.Count(&m.Star{})
+				So(stars, ShouldEqual, 0)
+				items, _ := x.Count(&m.PlaylistItem{})
+				So(items, ShouldEqual, 0)
+
+				query := m.GetDashboardQuery{Slug: "test-dash-67", OrgId: 1}
+				So(GetDashboard(&query), ShouldBeNil)
+			})
+
+			Convey("Should not delete any dashboard when one id belongs to another org", func() {
+				other := insertTestDashboard("other org dash", 2)
+
+				err := DeleteDashboards(&m.DeleteDashboardsCommand{
+					DashboardIds: []int64{savedDash.Id, other.Id},
+					OrgId:        1,
+				})
+				So(err, ShouldEqual, m.ErrDashboardNotFound)
+
+				query := m.GetDashboardQuery{Id: savedDash.Id, OrgId: 1}
+				So(GetDashboard(&query), ShouldBeNil)
+			})
+
 			Convey("Should return error if no dashboard is updated", func() {
 				cmd := m.SaveDashboardCommand{
 					OrgId:     1,
@@ -102,6 +287,167 @@ func TestDashboardDataAccess(t *testing.T) {
 				So(err, ShouldNotBeNil)
 			})
 
+			Convey("Should not save dashboard when the context is cancelled", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				cmd := m.SaveDashboardCommand{
+					OrgId: 1,
+					Dashboard: simplejson.NewFromAny(map[string]interface{}{
+						"id":    nil,
+						"title": "cancelled dash",
+					}),
+				}
+
+				err := SaveDashboardCtx(ctx, &cmd)
+				So(err, ShouldEqual, context.Canceled)
+
+				query := m.GetDashboardQuery{Slug: "cancelled-dash", OrgId: 1}
+				err = GetDashboard(&query)
+				So(err, ShouldEqual, m.ErrDashboardNotFound)
+			})
+
+			Convey("Should pass the context of a bus dispatch on to the save", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				cmd := m.SaveDashboardCommand{
+					OrgId: 1,
+					Dashboard: simplejson.NewFromAny(map[string]interface{}{
+						"id":    nil,
+						"title": "cancelled dash",
+					}),
+				}
+
+				err := bus.DispatchCtx(ctx, &cmd)
+				So(err, ShouldEqual, context.Canceled)
+
+				err = bus.Dispatch(&cmd)
+				So(err, ShouldBeNil)
+				So(cmd.Result.Slug, ShouldEqual, "cancelled-dash")
+			})
+
+			Convey("Should save all tags of a dashboard with many tags", func() {
+				tags := make([]interface{}, 0)
+				for i := 0; i < 50; i++ {
+					tags = append(tags, fmt.Sprintf("tag-%02d", i))
+				}
+
+				dash := insertTestDashboard("many tags", 1, tags...)
+
+				var terms []string
+				err := x.Table("dashboard_tag").Where("dashboard_id=?", dash.Id).Asc("term").Cols("term").Find(&terms)
+				So(err, ShouldBeNil)
+
+				So(len(terms), ShouldEqual, 50)
+				So(terms[0], ShouldEqual, "tag-00")
+				So(terms[49], ShouldEqual, "tag-49")
+			})
+
+			Convey("Should not rewrite tags when they did not change", func() {
+				tagIds := func() []int64 {
+					var ids []int64
+					err := x.Table("dashboard_tag").Where("dashboard_id=?", savedDash.Id).Asc("id").Cols("id").Find(&ids)
+					So(err, ShouldBeNil)
+					return ids
+				}
+
+				before := tagIds()
+				So(len(before), ShouldEqual, 2)
+
+				saveTestDashboardVersion(savedDash, "same tags")
+				So(tagIds(), ShouldResemble, before)
+
+				Convey("but rewrite them when they changed", func() {
+					updateTestDashboard(savedDash, map[string]interface{}{
+						"id":   savedDash.Id,
+						"tags": []interface{}{"prod"},
+					})
+
+					after := tagIds()
+					So(len(after), ShouldEqual, 1)
+					So(after[0], ShouldNotEqual, before[0])
+				})
+			})
+
+			Convey("Should return the previous version", func() {
+				createCmd := m.SaveDashboardCommand{
+					OrgId:     1,
+					Dashboard: simplejson.NewFromAny(map[string]interface{}{"id": nil, "title": "versioned dash"}),
+				}
+				So(SaveDashboard(&createCmd), ShouldBeNil)
+				So(createCmd.PreviousVersion, ShouldEqual, 0)
+				So(createCmd.Result.Version, ShouldEqual, 1)
+
+				updateCmd := m.SaveDashboardCommand{
+					OrgId: 1,
+					Dashboard: simplejson.NewFromAny(map[string]interface{}{
+						"id":      createCmd.Result.Id,
+						"title":   "versioned dash",
+						"version": createCmd.Result.Version,
+					}),
+				}
+				So(SaveDashboard(&updateCmd), ShouldBeNil)
+				So(updateCmd.PreviousVersion, ShouldEqual, 1)
+				So(updateCmd.Result.Version, ShouldEqual, 2)
+			})
+
+			Convey("Should save several dashboards at once", func() {
+				cmd := m.SaveDashboardsCommand{
+					Dashboards: []*m.SaveDashboardCommand{
+						{OrgId: 1, Dashboard: simplejson.NewFromAny(map[string]interface{}{"id": nil, "title": "bulk dash 1"})},
+						{OrgId: 1, Dashboard: simplejson.NewFromAny(map[string]interface{}{"id": nil, "title": "bulk dash 2"})},
+					},
+				}
+
+				err := SaveDashboards(&cmd)
+				So(err, ShouldBeNil)
+				So(len(cmd.Result), ShouldEqual, 2)
+				So(cmd.Result[0].Id, ShouldNotEqual, 0)
+				So(cmd.Result[1].Title, ShouldEqual, "bulk dash 2")
+			})
+
+			Convey("Should not save any dashboard when one of them fails", func() {
+				cmd := m.SaveDashboardsCommand{
+					Dashboards: []*m.SaveDashboardCommand{
+						{OrgId: 1, Dashboard: simplejson.NewFromAny(map[string]interface{}{"id": nil, "title": "bulk dash 1"})},
+						{OrgId: 1, Dashboard: simplejson.NewFromAny(map[string]interface{}{"id": nil, "title": "test dash 23"})},
+					},
+				}
+
+				err := SaveDashboards(&cmd)
+				So(err, ShouldNotBeNil)
+
+				saveErr, ok := err.(m.SaveDashboardsError)
+				So(ok, ShouldBeTrue)
+				So(saveErr.Index, ShouldEqual, 1)
+				So(saveErr.Title, ShouldEqual, "test dash 23")
+				So(saveErr.Err, ShouldEqual, m.ErrDashboardWithSameNameExists)
+
+				query := m.GetDashboardQuery{Slug: "bulk-dash-1", OrgId: 1}
+				err = GetDashboard(&query)
+				So(err, ShouldEqual, m.ErrDashboardNotFound)
+			})
+
+			Convey("Should roll back all dashboards on a version mismatch", func() {
+				cmd := m.SaveDashboardsCommand{
+					Dashboards: []*m.SaveDashboardCommand{
+						{OrgId: 1, Dashboard: simplejson.NewFromAny(map[string]interface{}{"id": nil, "title": "bulk dash 1"})},
+						{OrgId: 1, Dashboard: simplejson.NewFromAny(map[string]interface{}{
+							"id":      savedDash.Id,
+							"title":   "test dash 23",
+							"version": savedDash.Version + 1,
+						})},
+					},
+				}
+
+				err := SaveDashboards(&cmd)
+				So(err.(m.SaveDashboardsError).Err, ShouldEqual, m.ErrDashboardVersionMismatch)
+
+				query := m.GetDashboardQuery{Slug: "bulk-dash-1", OrgId: 1}
+				So(GetDashboard(&query), ShouldEqual, m.ErrDashboardNotFound)
+			})
+
 			Convey("Should be able to search for dashboard", func() {
 				query := search.FindPersistedDashboardsQuery{
 					Title: "test dash 23",
@@ -116,81 +462,2018 @@ func TestDashboardDataAccess(t *testing.T) {
 				So(len(hit.Tags), ShouldEqual, 2)
 			})
 
-			Convey("Should be able to search for dashboard by dashboard ids", func() {
-				Convey("should be able to find two dashboards by id", func() {
+			Convey("Should return when and by whom a dashboard was last updated", func() {
+				cmd := m.SaveDashboardCommand{
+					OrgId:     1,
+					UserId:    7,
+					Overwrite: true,
+					Dashboard: simplejson.NewFromAny(map[string]interface{}{
+						"id":    savedDash.Id,
+						"title": "test dash 23",
+						"tags":  []interface{}{"prod", "webapp"},
+					}),
+				}
+				err := SaveDashboard(&cmd)
+				So(err, ShouldBeNil)
+
+				query := search.FindPersistedDashboardsQuery{
+					Title: "test dash 23",
+					OrgId: 1,
+				}
+
+				err = SearchDashboards(&query)
+				So(err, ShouldBeNil)
+
+				So(len(query.Result), ShouldEqual, 1)
+				hit := query.Result[0]
+				dashQuery := m.GetDashboardQuery{Id: savedDash.Id, OrgId: 1}
+				err = GetDashboard(&dashQuery)
+				So(err, ShouldBeNil)
+
+				So(hit.UpdatedBy, ShouldEqual, 7)
+				So(hit.Updated.IsZero(), ShouldBeFalse)
+				So(hit.Updated.Unix(), ShouldEqual, dashQuery.Result.Updated.Unix())
+				So(len(hit.Tags), ShouldEqual, 2)
+			})
+
+			Convey("Should be able to search for dashboard by title match mode", func() {
+				insertTestDashboard("test dash", 1)
+				insertTestDashboard("my test dash", 1)
+
+				searchTitles := func(title string, match search.TitleMatch) []string {
 					query := search.FindPersistedDashboardsQuery{
-						DashboardIds: []int{1, 2},
-						OrgId:        1,
+						Title:      title,
+						TitleMatch: match,
+						OrgId:      1,
 					}
 
 					err := SearchDashboards(&query)
 					So(err, ShouldBeNil)
 
-					So(len(query.Result), ShouldEqual, 2)
+					titles := []string{}
+					for _, hit := range query.Result {
+						titles = append(titles, hit.Title)
+					}
+					return titles
+				}
 
-					hit := query.Result[0]
-					So(len(hit.Tags), ShouldEqual, 2)
+				Convey("contains is the default", func() {
+					So(searchTitles("test dash", ""), ShouldResemble, []string{"my test dash", "test dash", "test dash 23", "test dash 45", "test dash 67"})
+					So(searchTitles("test dash", search.TitleMatchContains), ShouldResemble, searchTitles("test dash", ""))
+				})
 
-					hit2 := query.Result[1]
-					So(len(hit2.Tags), ShouldEqual, 1)
+				Convey("prefix only matches titles starting with the term", func() {
+					So(searchTitles("Test Dash", search.TitleMatchPrefix), ShouldResemble, []string{"test dash", "test dash 23", "test dash 45", "test dash 67"})
 				})
 
-				Convey("DashboardIds that does not exists should not cause errors", func() {
-					query := search.FindPersistedDashboardsQuery{
-						DashboardIds: []int{1000},
-						OrgId:        1,
-					}
+				Convey("exact only matches the whole title", func() {
+					So(searchTitles("Test Dash", search.TitleMatchExact), ShouldResemble, []string{"test dash"})
+				})
+			})
+
+			Convey("Should be able to page through search results", func() {
+				insertTestDashboard("test dash 89", 1)
+
+				query := search.FindPersistedDashboardsQuery{OrgId: 1, Limit: 2, Page: 2}
+				err := SearchDashboards(&query)
+				So(err, ShouldBeNil)
+
+				So(len(query.Result), ShouldEqual, 2)
+				So(query.Result[0].Title, ShouldEqual, "test dash 67")
+				So(query.Result[1].Title, ShouldEqual, "test dash 89")
+
+				Convey("and limit dashboards rather than tags", func() {
+					So(len(query.Result[0].Tags), ShouldEqual, 2)
+				})
 
+				Convey("and return nothing past the last page", func() {
+					query := search.FindPersistedDashboardsQuery{OrgId: 1, Limit: 2, Page: 3}
 					err := SearchDashboards(&query)
 					So(err, ShouldBeNil)
 					So(len(query.Result), ShouldEqual, 0)
 				})
 			})
 
-			Convey("Should not be able to save dashboard with same name", func() {
-				cmd := m.SaveDashboardCommand{
-					OrgId: 1,
-					Dashboard: simplejson.NewFromAny(map[string]interface{}{
-						"id":    nil,
-						"title": "test dash 23",
-						"tags":  []interface{}{},
-					}),
+			Convey("Should page through dashboards with the same title in id order", func() {
+				ids := make([]int64, 0)
+				for _, slug := range []string{"same-title-c", "same-title-a", "same-title-b"} {
+					dash := m.NewDashboard("same title")
+					dash.OrgId = 1
+					dash.Slug = slug
+					dash.Created = time.Now()
+					dash.Updated = time.Now()
+					_, err := x.Insert(dash)
+					So(err, ShouldBeNil)
+					ids = append(ids, dash.Id)
 				}
 
-				err := SaveDashboard(&cmd)
-				So(err, ShouldNotBeNil)
-			})
+				pageIds := func(page int) []int64 {
+					query := search.FindPersistedDashboardsQuery{OrgId: 1, Title: "same title", TitleMatch: search.TitleMatchExact, Limit: 2, Page: page}
+					err := SearchDashboards(&query)
+					So(err, ShouldBeNil)
 
-			Convey("Should be able to get dashboard tags", func() {
-				query := m.GetDashboardTagsQuery{OrgId: 1}
+					result := []int64{}
+					for _, hit := range query.Result {
+						result = append(result, hit.Id)
+					}
+					return result
+				}
 
-				err := GetDashboardTags(&query)
+				So(pageIds(1), ShouldResemble, ids[:2])
+				So(pageIds(2), ShouldResemble, ids[2:])
+			})
+
+			Convey("Should be able to sort search results", func() {
+				_, err := x.Exec("UPDATE dashboard SET updated = ? WHERE id = ?", time.Now().Add(time.Hour), savedDash.Id)
 				So(err, ShouldBeNil)
 
-				So(len(query.Result), ShouldEqual, 2)
-			})
+				sortedTitles := func(sort search.SortOrder) []string {
+					query := search.FindPersistedDashboardsQuery{OrgId: 1, Sort: sort}
+					err := SearchDashboards(&query)
+					So(err, ShouldBeNil)
 
-			Convey("Given two dashboards, one is starred dashboard by user 10, other starred by user 1", func() {
-				starredDash := insertTestDashboard("starred dash", 1)
-				StarDashboard(&m.StarDashboardCommand{
-					DashboardId: starredDash.Id,
-					UserId:      10,
-				})
+					titles := []string{}
+					for _, hit := range query.Result {
+						titles = append(titles, hit.Title)
+					}
+					return titles
+				}
 
-				StarDashboard(&m.StarDashboardCommand{
-					DashboardId: savedDash.Id,
-					UserId:      1,
+				So(sortedTitles(""), ShouldResemble, []string{"test dash 23", "test dash 45", "test dash 67"})
+				So(sortedTitles(search.SortAlphaDesc), ShouldResemble, []string{"test dash 67", "test dash 45", "test dash 23"})
+				So(sortedTitles(search.SortUpdatedDesc)[0], ShouldEqual, "test dash 23")
+
+				Convey("and fall back to title order for unknown sort options", func() {
+					So(sortedTitles("title; DROP TABLE dashboard"), ShouldResemble, sortedTitles(search.SortAlphaAsc))
 				})
 
-				Convey("Should be able to search for starred dashboards", func() {
-					query := search.FindPersistedDashboardsQuery{OrgId: 1, UserId: 10, IsStarred: true}
-					err := SearchDashboards(&query)
+				Convey("and order dashboards with the same title and update time by id", func() {
+					updated := time.Now().Add(-time.Hour)
+					ids := make([]int64, 0)
+					for _, slug := range []string{"same-title-b", "same-title-a"} {
+						dash := m.NewDashboard("same title")
+						dash.OrgId = 1
+						dash.Slug = slug
+						dash.Created = updated
+						dash.Updated = updated
+						_, err := x.Insert(dash)
+						So(err, ShouldBeNil)
+						ids = append(ids, dash.Id)
+					}
 
-					So(err, ShouldBeNil)
-					So(len(query.Result), ShouldEqual, 1)
-					So(query.Result[0].Title, ShouldEqual, "starred dash")
+					sortedIds := func(sort search.SortOrder) []int64 {
+						query := search.FindPersistedDashboardsQuery{OrgId: 1, Title: "same title", TitleMatch: search.TitleMatchExact, Sort: sort}
+						err := SearchDashboards(&query)
+						So(err, ShouldBeNil)
+
+						result := []int64{}
+						for _, hit := range query.Result {
+							result = append(result, hit.Id)
+						}
+						return result
+					}
+
+					So(sortedIds(search.SortAlphaDesc), ShouldResemble, ids)
+					So(sortedIds(search.SortUpdatedDesc), ShouldResemble, ids)
 				})
 			})
-		})
-	})
+
+			Convey("Should be able to search for dashboards matching all or any tags", func() {
+				insertTestDashboard("tagged a b", 1, "a", "b")
+
+				searchTags := func(tags []string, match search.TagMatch) search.HitList {
+					query := search.FindPersistedDashboardsQuery{OrgId: 1, Tags: tags, TagMatch: match}
+					err := SearchDashboards(&query)
+					So(err, ShouldBeNil)
+					return query.Result
+				}
+
+				So(len(searchTags([]string{"a", "c"}, search.TagMatchAll)), ShouldEqual, 0)
+				So(len(searchTags([]string{"a", "c"}, "")), ShouldEqual, 0)
+
+				hits := searchTags([]string{"a", "c"}, search.TagMatchAny)
+				So(len(hits), ShouldEqual, 1)
+				So(hits[0].Title, ShouldEqual, "tagged a b")
+				So(len(hits[0].Tags), ShouldEqual, 2)
+
+				So(len(searchTags([]string{"a", "b"}, search.TagMatchAll)), ShouldEqual, 1)
+
+				Convey("and ignore repeated tags", func() {
+					So(len(searchTags([]string{"a", "a"}, search.TagMatchAll)), ShouldEqual, 1)
+				})
+			})
+
+			Convey("Should be able to search for dashboards updated in a time range", func() {
+				now := time.Now()
+				for id, age := range map[int64]time.Duration{1: 48 * time.Hour, 2: 12 * time.Hour, 3: time.Hour} {
+					_, err := x.Exec("UPDATE dashboard SET updated = ? WHERE id = ?", now.Add(-age), id)
+					So(err, ShouldBeNil)
+				}
+
+				searchUpdated := func(after, before time.Time) []string {
+					query := search.FindPersistedDashboardsQuery{OrgId: 1, UpdatedAfter: after, UpdatedBefore: before}
+					err := SearchDashboards(&query)
+					So(err, ShouldBeNil)
+
+					titles := []string{}
+					for _, hit := range query.Result {
+						titles = append(titles, hit.Title)
+					}
+					return titles
+				}
+
+				So(searchUpdated(now.Add(-24*time.Hour), time.Time{}), ShouldResemble, []string{"test dash 45", "test dash 67"})
+				So(searchUpdated(time.Time{}, now.Add(-6*time.Hour)), ShouldResemble, []string{"test dash 23", "test dash 45"})
+				So(searchUpdated(now.Add(-24*time.Hour), now.Add(-6*time.Hour)), ShouldResemble, []string{"test dash 45"})
+				So(searchUpdated(time.Time{}, time.Time{}), ShouldResemble, []string{"test dash 23", "test dash 45", "test dash 67"})
+			})
+
+			Convey("Should add each tag once when building search hits", func() {
+				hits := makeQueryResult([]DashboardSearchProjection{
+					{Id: 2, Title: "b", Slug: "b", Term: "prod"},
+					{Id: 1, Title: "a", Slug: "a", Term: "prod"},
+					{Id: 2, Title: "b", Slug: "b", Term: "webapp"},
+					{Id: 2, Title: "b", Slug: "b", Term: "prod"},
+					{Id: 1, Title: "a", Slug: "a", Term: ""},
+					{Id: 1, Title: "a", Slug: "a", Term: "prod"},
+				})
+
+				So(len(hits), ShouldEqual, 2)
+				So(hits[0].Id, ShouldEqual, 2)
+				So(hits[0].Uri, ShouldEqual, "db/b")
+				So(hits[0].Tags, ShouldResemble, []string{"prod", "webapp"})
+				So(hits[1].Id, ShouldEqual, 1)
+				So(hits[1].Tags, ShouldResemble, []string{"prod"})
+			})
+
+			Convey("Should be able to search for dashboard by title and tags", func() {
+				insertTestDashboard("nginx prod", 1, "prod", "nginx")
+				insertTestDashboard("nginx staging", 1, "staging", "nginx")
+				insertTestDashboard("apache prod", 1, "prod")
+
+				query := search.FindPersistedDashboardsQuery{
+					Title: "nginx",
+					Tags:  []string{"prod"},
+					OrgId: 1,
+				}
+
+				err := SearchDashboards(&query)
+				So(err, ShouldBeNil)
+
+				So(len(query.Result), ShouldEqual, 1)
+				hit := query.Result[0]
+				So(hit.Title, ShouldEqual, "nginx prod")
+
+				Convey("and return all tags of the matching dashboard", func() {
+					So(len(hit.Tags), ShouldEqual, 2)
+				})
+			})
+
+			Convey("Should be able to search for dashboard by multiple tags", func() {
+				query := search.FindPersistedDashboardsQuery{
+					Tags:  []string{"prod", "webapp"},
+					OrgId: 1,
+				}
+
+				err := SearchDashboards(&query)
+				So(err, ShouldBeNil)
+
+				So(len(query.Result), ShouldEqual, 2)
+				So(query.Result[0].Title, ShouldEqual, "test dash 23")
+				So(query.Result[1].Title, ShouldEqual, "test dash 67")
+			})
+
+			Convey("Should be able to search for dashboard by dashboard ids", func() {
+				Convey("should be able to find two dashboards by id", func() {
+					query := search.FindPersistedDashboardsQuery{
+						DashboardIds: []int{1, 2},
+						OrgId:        1,
+					}
+
+					err := SearchDashboards(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 2)
+
+					hit := query.Result[0]
+					So(len(hit.Tags), ShouldEqual, 2)
+
+					hit2 := query.Result[1]
+					So(len(hit2.Tags), ShouldEqual, 1)
+				})
+
+				Convey("DashboardIds that does not exists should not cause errors", func() {
+					query := search.FindPersistedDashboardsQuery{
+						DashboardIds: []int{1000},
+						OrgId:        1,
+					}
+
+					err := SearchDashboards(&query)
+					So(err, ShouldBeNil)
+					So(len(query.Result), ShouldEqual, 0)
+				})
+			})
+
+			Convey("Should not be able to save dashboard with same name", func() {
+				cmd := m.SaveDashboardCommand{
+					OrgId: 1,
+					Dashboard: simplejson.NewFromAny(map[string]interface{}{
+						"id":    nil,
+						"title": "test dash 23",
+						"tags":  []interface{}{},
+					}),
+				}
+
+				err := SaveDashboard(&cmd)
+				So(err, ShouldNotBeNil)
+			})
+
+			Convey("Should be able to get dashboard tags", func() {
+				query := m.GetDashboardTagsQuery{OrgId: 1}
+
+				err := GetDashboardTags(&query)
+				So(err, ShouldBeNil)
+
+				So(len(query.Result), ShouldEqual, 2)
+			})
+
+			Convey("Should be able to get dashboards by tag combinations", func() {
+				insertTestDashboard("staging api", 1, "staging", "api")
+				insertTestDashboard("staging web", 1, "staging", "web")
+
+				Convey("with non-overlapping tag sets", func() {
+					query := m.GetDashboardsByTagCombinationsQuery{
+						OrgId:   1,
+						TagSets: [][]string{{"prod", "webapp"}, {"staging", "api"}},
+					}
+
+					err := GetDashboardsByTagCombinations(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 3)
+					So(query.Result[0].Title, ShouldEqual, "staging api")
+					So(query.Result[1].Title, ShouldEqual, "test dash 23")
+					So(query.Result[2].Title, ShouldEqual, "test dash 67")
+				})
+
+				Convey("with overlapping tag sets should not return duplicates", func() {
+					query := m.GetDashboardsByTagCombinationsQuery{
+						OrgId:   1,
+						TagSets: [][]string{{"prod"}, {"prod", "webapp"}},
+					}
+
+					err := GetDashboardsByTagCombinations(&query)
+					So(err, ShouldBeNil)
+					So(len(query.Result), ShouldEqual, 3)
+				})
+
+				Convey("with no tag sets should return nothing", func() {
+					query := m.GetDashboardsByTagCombinationsQuery{OrgId: 1}
+
+					err := GetDashboardsByTagCombinations(&query)
+					So(err, ShouldBeNil)
+					So(len(query.Result), ShouldEqual, 0)
+				})
+			})
+
+			Convey("Given dashboards with alerts of different severities", func() {
+				criticalDash := insertTestDashboard("critical dash", 1)
+				mixedDash := insertTestDashboard("mixed dash", 1)
+
+				insertTestAlerts(criticalDash, &m.Alert{PanelId: 1, Name: "cpu", Severity: "critical"})
+				insertTestAlerts(mixedDash,
+					&m.Alert{PanelId: 1, Name: "cpu", Severity: "critical"},
+					&m.Alert{PanelId: 2, Name: "disk", Severity: "warning"},
+					&m.Alert{PanelId: 3, Name: "mem", Severity: "warning"},
+				)
+
+				Convey("Should find dashboards with critical alerts", func() {
+					query := m.GetDashboardsByAlertSeverityQuery{OrgId: 1, Severity: "critical"}
+
+					err := GetDashboardsByAlertSeverity(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 2)
+					So(query.Result[0].Id, ShouldEqual, criticalDash.Id)
+					So(query.Result[1].Id, ShouldEqual, mixedDash.Id)
+				})
+
+				Convey("Should find dashboards with warning alerts only once", func() {
+					query := m.GetDashboardsByAlertSeverityQuery{OrgId: 1, Severity: "warning"}
+
+					err := GetDashboardsByAlertSeverity(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].Id, ShouldEqual, mixedDash.Id)
+				})
+
+				Convey("Should not find dashboards in another org", func() {
+					query := m.GetDashboardsByAlertSeverityQuery{OrgId: 2, Severity: "critical"}
+
+					err := GetDashboardsByAlertSeverity(&query)
+					So(err, ShouldBeNil)
+					So(len(query.Result), ShouldEqual, 0)
+				})
+			})
+
+			Convey("Given dashboards with alerts notifying 0, 1 and 5 channels", func() {
+				channels := make([]interface{}, 0)
+				for i := 0; i < 5; i++ {
+					cmd := m.CreateAlertNotificationCommand{
+						Name:     fmt.Sprintf("channel %d", i),
+						Type:     "email",
+						OrgId:    1,
+						Settings: simplejson.New(),
+					}
+					So(CreateAlertNotificationCommand(&cmd), ShouldBeNil)
+					channels = append(channels, map[string]interface{}{"id": cmd.Result.Id})
+				}
+
+				withChannels := func(ids ...interface{}) *simplejson.Json {
+					return simplejson.NewFromAny(map[string]interface{}{"notifications": ids})
+				}
+
+				noneDash := insertTestDashboard("no channels", 1)
+				oneDash := insertTestDashboard("one channel", 1)
+				fiveDash := insertTestDashboard("five channels", 1)
+
+				insertTestAlerts(noneDash, &m.Alert{PanelId: 1, Name: "a", Settings: withChannels()})
+				insertTestAlerts(oneDash, &m.Alert{PanelId: 1, Name: "a", Settings: withChannels(channels[0])})
+				insertTestAlerts(fiveDash,
+					&m.Alert{PanelId: 1, Name: "a", Settings: withChannels(channels[0], channels[1], channels[2])},
+					&m.Alert{PanelId: 2, Name: "b", Settings: withChannels(channels[2], channels[3], channels[4])},
+				)
+
+				Convey("Should return all alerted dashboards ordered by channel count", func() {
+					query := m.GetDashboardsByAlertNotificationCountQuery{OrgId: 1}
+
+					err := GetDashboardsByAlertNotificationCount(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 3)
+					So(query.Result[0].DashboardId, ShouldEqual, fiveDash.Id)
+					So(query.Result[0].NotificationCount, ShouldEqual, 5)
+					So(query.Result[1].DashboardId, ShouldEqual, oneDash.Id)
+					So(query.Result[1].NotificationCount, ShouldEqual, 1)
+					So(query.Result[2].DashboardId, ShouldEqual, noneDash.Id)
+					So(query.Result[2].NotificationCount, ShouldEqual, 0)
+				})
+
+				Convey("Should filter by minimum channel count", func() {
+					query := m.GetDashboardsByAlertNotificationCountQuery{OrgId: 1, MinChannels: 2}
+
+					err := GetDashboardsByAlertNotificationCount(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].DashboardId, ShouldEqual, fiveDash.Id)
+				})
+
+				Convey("Should not count deleted channels", func() {
+					id, _ := simplejson.NewFromAny(channels[0]).Get("id").Int64()
+					err := DeleteAlertNotification(&m.DeleteAlertNotificationCommand{Id: id, OrgId: 1})
+					So(err, ShouldBeNil)
+
+					query := m.GetDashboardsByAlertNotificationCountQuery{OrgId: 1, MinChannels: 1}
+
+					err = GetDashboardsByAlertNotificationCount(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].NotificationCount, ShouldEqual, 4)
+				})
+			})
+
+			Convey("Given dashboards with different refresh rates", func() {
+				insertTestDashboardWithData("refresh 5s", 1, map[string]interface{}{"refresh": "5s"})
+				insertTestDashboardWithData("refresh 30s", 1, map[string]interface{}{"refresh": "30s"})
+				insertTestDashboardWithData("refresh 1m", 1, map[string]interface{}{"refresh": "1m"})
+				insertTestDashboardWithData("refresh off", 1, map[string]interface{}{"refresh": false})
+
+				Convey("Should find dashboards refreshing at least every 30 seconds", func() {
+					query := m.GetDashboardsByRefreshRateQuery{OrgId: 1, MaxRefreshSeconds: 30}
+
+					err := GetDashboardsByRefreshRate(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 2)
+					So(query.Result[0].Title, ShouldEqual, "refresh 30s")
+					So(query.Result[1].Title, ShouldEqual, "refresh 5s")
+				})
+
+				Convey("Should include interval equal to the max", func() {
+					query := m.GetDashboardsByRefreshRateQuery{OrgId: 1, MaxRefreshSeconds: 60}
+
+					err := GetDashboardsByRefreshRate(&query)
+					So(err, ShouldBeNil)
+					So(len(query.Result), ShouldEqual, 3)
+				})
+
+				Convey("Should find dashboards across batches", func() {
+					defer func(size int) { dashboardBatchSize = size }(dashboardBatchSize)
+					dashboardBatchSize = 1
+
+					query := m.GetDashboardsByRefreshRateQuery{OrgId: 1, MaxRefreshSeconds: 60}
+
+					err := GetDashboardsByRefreshRate(&query)
+					So(err, ShouldBeNil)
+					So(len(query.Result), ShouldEqual, 3)
+				})
+
+				Convey("Should never include dashboards with refresh off", func() {
+					query := m.GetDashboardsByRefreshRateQuery{OrgId: 1, MaxRefreshSeconds: 86400}
+
+					err := GetDashboardsByRefreshRate(&query)
+					So(err, ShouldBeNil)
+
+					for _, dash := range query.Result {
+						So(dash.Title, ShouldNotEqual, "refresh off")
+					}
+				})
+			})
+
+			Convey("Given dashboards with template variables", func() {
+				templating := func(name string, value interface{}) map[string]interface{} {
+					return map[string]interface{}{
+						"templating": map[string]interface{}{
+							"list": []interface{}{
+								map[string]interface{}{"name": "datacenter", "current": map[string]interface{}{"value": "eu"}},
+								map[string]interface{}{"name": name, "current": map[string]interface{}{"value": value}},
+							},
+						},
+					}
+				}
+
+				insertTestDashboardWithData("env default", 1, templating("env", "prod"))
+				insertTestDashboardWithData("env overridden", 1, templating("env", "staging"))
+				insertTestDashboardWithData("env multi default", 1, templating("env", []interface{}{"prod"}))
+				insertTestDashboardWithData("env multi overridden", 1, templating("env", []interface{}{"prod", "staging"}))
+				insertTestDashboardWithData("other variable", 1, templating("host", "web-1"))
+
+				Convey("Should find dashboards where the variable is not the default", func() {
+					query := m.GetDashboardsByVariableWithDefaultQuery{OrgId: 1, VariableName: "env", DefaultValue: "prod"}
+
+					err := GetDashboardsByVariableWithDefault(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 2)
+					So(query.Result[0].Title, ShouldEqual, "env multi overridden")
+					So(query.Result[1].Title, ShouldEqual, "env overridden")
+				})
+
+				Convey("Should not match dashboards without the variable", func() {
+					query := m.GetDashboardsByVariableWithDefaultQuery{OrgId: 1, VariableName: "host", DefaultValue: "web-1"}
+
+					err := GetDashboardsByVariableWithDefault(&query)
+					So(err, ShouldBeNil)
+					So(len(query.Result), ShouldEqual, 0)
+				})
+			})
+
+			Convey("Given dashboards with relative and pinned time ranges", func() {
+				insertTestDashboardWithData("relative", 1, map[string]interface{}{
+					"time": map[string]interface{}{"from": "now-1h", "to": "now"},
+				})
+				insertTestDashboardWithData("pinned", 1, map[string]interface{}{
+					"time": map[string]interface{}{"from": "1609459200000", "to": "1609462800000"},
+				})
+
+				Convey("Should only find the pinned dashboard", func() {
+					query := m.GetDashboardsByPinnedTimeRangeQuery{OrgId: 1}
+
+					err := GetDashboardsByPinnedTimeRange(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].Title, ShouldEqual, "pinned")
+				})
+
+				Convey("Should find pinned dashboards with relative panel time ranges", func() {
+					insertTestDashboardWithData("pinned with panel time", 1, map[string]interface{}{
+						"panels": []interface{}{
+							map[string]interface{}{"id": 1, "type": "graph", "time": map[string]interface{}{"from": "now-5m", "to": "now"}},
+						},
+						"time": map[string]interface{}{"from": "1609459200000", "to": "1609462800000"},
+					})
+
+					query := m.GetDashboardsByPinnedTimeRangeQuery{OrgId: 1}
+
+					err := GetDashboardsByPinnedTimeRange(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 2)
+					So(query.Result[0].Title, ShouldEqual, "pinned")
+					So(query.Result[1].Title, ShouldEqual, "pinned with panel time")
+				})
+			})
+
+			Convey("Given dashboards with schema versions 10, 22 and 36", func() {
+				v10 := insertTestDashboardWithData("schema 10", 1, map[string]interface{}{"schemaVersion": 10})
+				v22 := insertTestDashboardWithData("schema 22", 1, map[string]interface{}{"schemaVersion": 22})
+				insertTestDashboardWithData("schema 36", 1, map[string]interface{}{"schemaVersion": 36})
+
+				Convey("Should find dashboards below the schema version", func() {
+					query := m.GetDashboardsBelowSchemaVersionQuery{OrgId: 1, MaxSchemaVersion: 23}
+
+					err := GetDashboardsBelowSchemaVersion(&query)
+					So(err, ShouldBeNil)
+
+					// dashboards without schemaVersion count as version 0
+					So(len(query.Result), ShouldEqual, 5)
+					So(query.Result[3].Id, ShouldEqual, v10.Id)
+					So(query.Result[4].Id, ShouldEqual, v22.Id)
+				})
+
+				Convey("Should page through results", func() {
+					query := m.GetDashboardsBelowSchemaVersionQuery{OrgId: 1, MaxSchemaVersion: 23, Page: 2, Limit: 3}
+
+					err := GetDashboardsBelowSchemaVersion(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 2)
+					So(query.Result[0].Id, ShouldEqual, v10.Id)
+				})
+
+				Convey("Should compare schema versions as numbers", func() {
+					v9 := insertTestDashboardWithData("schema 9", 1, map[string]interface{}{"schemaVersion": 9, "style": "dark"})
+
+					query := m.GetDashboardsBelowSchemaVersionQuery{OrgId: 1, MaxSchemaVersion: 10}
+
+					err := GetDashboardsBelowSchemaVersion(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 4)
+					So(query.Result[3].Id, ShouldEqual, v9.Id)
+				})
+
+				Convey("Should only read the top level schema version", func() {
+					insertTestDashboardWithData("schema 36 with nested version", 1, map[string]interface{}{
+						"panels": []interface{}{
+							map[string]interface{}{"id": 1, "type": "plugin", "schemaVersion": 1},
+						},
+						"schemaVersion": 36,
+					})
+
+					query := m.GetDashboardsBelowSchemaVersionQuery{OrgId: 1, MaxSchemaVersion: 23}
+
+					err := GetDashboardsBelowSchemaVersion(&query)
+					So(err, ShouldBeNil)
+					So(len(query.Result), ShouldEqual, 5)
+				})
+
+				Convey("Should return empty page past the end", func() {
+					query := m.GetDashboardsBelowSchemaVersionQuery{OrgId: 1, MaxSchemaVersion: 23, Page: 3, Limit: 3}
+
+					err := GetDashboardsBelowSchemaVersion(&query)
+					So(err, ShouldBeNil)
+					So(len(query.Result), ShouldEqual, 0)
+				})
+			})
+
+			Convey("Given dashboards using prometheus and loki datasources", func() {
+				for _, ds := range []m.AddDataSourceCommand{
+					{OrgId: 1, Name: "prom", Type: "prometheus", Access: m.DS_ACCESS_PROXY, IsDefault: true},
+					{OrgId: 1, Name: "logs", Type: "loki", Access: m.DS_ACCESS_PROXY},
+				} {
+					So(AddDataSource(&ds), ShouldBeNil)
+				}
+
+				withPanels := func(panels ...interface{}) map[string]interface{} {
+					return map[string]interface{}{"panels": panels}
+				}
+
+				insertTestDashboardWithData("prometheus by name", 1, withPanels(
+					map[string]interface{}{"id": 1, "type": "graph", "datasource": "prom"},
+				))
+				insertTestDashboardWithData("prometheus as default", 1, withPanels(
+					map[string]interface{}{"id": 1, "type": "graph", "datasource": nil},
+				))
+				insertTestDashboardWithData("loki in mixed panel", 1, withPanels(
+					map[string]interface{}{"id": 1, "type": "graph", "datasource": "-- Mixed --", "targets": []interface{}{
+						map[string]interface{}{"datasource": "logs"},
+					}},
+				))
+
+				Convey("Should find prometheus dashboards including default datasource panels", func() {
+					query := m.GetDashboardsByDatasourceTypeQuery{OrgId: 1, DatasourceType: "prometheus"}
+
+					err := GetDashboardsByDatasourceType(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 2)
+					So(query.Result[0].Title, ShouldEqual, "prometheus as default")
+					So(query.Result[1].Title, ShouldEqual, "prometheus by name")
+				})
+
+				Convey("Should find loki dashboards through query datasources", func() {
+					query := m.GetDashboardsByDatasourceTypeQuery{OrgId: 1, DatasourceType: "loki"}
+
+					err := GetDashboardsByDatasourceType(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].Title, ShouldEqual, "loki in mixed panel")
+				})
+
+				Convey("Should return nothing for unused datasource types", func() {
+					query := m.GetDashboardsByDatasourceTypeQuery{OrgId: 1, DatasourceType: "graphite"}
+
+					err := GetDashboardsByDatasourceType(&query)
+					So(err, ShouldBeNil)
+					So(len(query.Result), ShouldEqual, 0)
+				})
+			})
+
+			Convey("Given a datasource with html characters in its name", func() {
+				ds := m.AddDataSourceCommand{OrgId: 1, Name: "<logs & more>", Type: "loki", Access: m.DS_ACCESS_PROXY}
+				So(AddDataSource(&ds), ShouldBeNil)
+
+				insertTestDashboardWithData("escaped datasource", 1, map[string]interface{}{
+					"panels": []interface{}{
+						map[string]interface{}{"id": 1, "type": "graph", "datasource": "<logs & more>"},
+					},
+				})
+
+				Convey("Should match the escaped name in the stored json", func() {
+					query := m.GetDashboardsByDatasourceTypeQuery{OrgId: 1, DatasourceType: "loki"}
+
+					err := GetDashboardsByDatasourceType(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].Title, ShouldEqual, "escaped datasource")
+				})
+			})
+
+			Convey("Given dashboards with 0 and 5 tags", func() {
+				insertTestDashboard("no tags", 1)
+				insertTestDashboard("five tags", 1, "a", "b", "c", "d", "e")
+
+				Convey("Should find untagged dashboards", func() {
+					query := m.GetDashboardsByTagCountQuery{OrgId: 1, MinTags: 0, MaxTags: 0}
+
+					err := GetDashboardsByTagCount(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].Title, ShouldEqual, "no tags")
+					So(query.Result[0].TagCount, ShouldEqual, 0)
+				})
+
+				Convey("Should include both boundaries", func() {
+					query := m.GetDashboardsByTagCountQuery{OrgId: 1, MinTags: 1, MaxTags: 5}
+
+					err := GetDashboardsByTagCount(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 4)
+					So(query.Result[0].Title, ShouldEqual, "five tags")
+					So(query.Result[0].TagCount, ShouldEqual, 5)
+					So(query.Result[3].Title, ShouldEqual, "test dash 45")
+					So(query.Result[3].TagCount, ShouldEqual, 1)
+				})
+
+				Convey("Should exclude dashboards outside the range", func() {
+					query := m.GetDashboardsByTagCountQuery{OrgId: 1, MinTags: 2, MaxTags: 4}
+
+					err := GetDashboardsByTagCount(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 2)
+					for _, item := range query.Result {
+						So(item.TagCount, ShouldEqual, 2)
+					}
+				})
+			})
+
+			Convey("Given dashboards with graph, table and stat panels", func() {
+				insertTestDashboardWithData("graphs in rows", 1, map[string]interface{}{
+					"rows": []interface{}{
+						map[string]interface{}{"panels": []interface{}{
+							map[string]interface{}{"id": 1, "type": "graph"},
+							map[string]interface{}{"id": 2, "type": "table"},
+						}},
+					},
+				})
+				insertTestDashboardWithData("stat panels", 1, map[string]interface{}{
+					"panels": []interface{}{
+						map[string]interface{}{"id": 1, "type": "stat"},
+					},
+				})
+				insertTestDashboardWithData("titled graph", 1, map[string]interface{}{
+					"panels": []interface{}{
+						map[string]interface{}{"id": 1, "type": "text", "title": "graph"},
+					},
+				})
+
+				Convey("Should find dashboards with graph panels", func() {
+					query := m.GetDashboardsByPanelPluginIdQuery{OrgId: 1, PanelPluginId: "graph"}
+
+					err := GetDashboardsByPanelPluginId(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].Title, ShouldEqual, "graphs in rows")
+				})
+
+				Convey("Should find dashboards with table panels", func() {
+					query := m.GetDashboardsByPanelPluginIdQuery{OrgId: 1, PanelPluginId: "table"}
+
+					err := GetDashboardsByPanelPluginId(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].Title, ShouldEqual, "graphs in rows")
+				})
+
+				Convey("Should find dashboards with stat panels", func() {
+					query := m.GetDashboardsByPanelPluginIdQuery{OrgId: 1, PanelPluginId: "stat"}
+
+					err := GetDashboardsByPanelPluginId(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].Title, ShouldEqual, "stat panels")
+				})
+			})
+
+			Convey("Given dashboards with active and expired alert silences", func() {
+				activeDash := insertTestDashboard("active silence", 1)
+				expiredDash := insertTestDashboard("expired silence", 1)
+
+				insertTestAlerts(activeDash, &m.Alert{PanelId: 1, Name: "a"}, &m.Alert{PanelId: 2, Name: "b"})
+				insertTestAlerts(expiredDash, &m.Alert{PanelId: 1, Name: "a"})
+
+				alerts := m.GetAlertsQuery{OrgId: 1}
+				So(HandleAlertsQuery(&alerts), ShouldBeNil)
+
+				alertIds := make(map[int64][]int64)
+				for _, alert := range alerts.Result {
+					alertIds[alert.DashboardId] = append(alertIds[alert.DashboardId], alert.Id)
+				}
+
+				until := time.Now().Add(2 * time.Hour)
+				So(SilenceAlert(&m.SilenceAlertCommand{OrgId: 1, AlertId: alertIds[activeDash.Id][0], Until: time.Now().Add(time.Hour)}), ShouldBeNil)
+				So(SilenceAlert(&m.SilenceAlertCommand{OrgId: 1, AlertId: alertIds[activeDash.Id][1], Until: until}), ShouldBeNil)
+				So(SilenceAlert(&m.SilenceAlertCommand{OrgId: 1, AlertId: alertIds[expiredDash.Id][0], Until: time.Now().Add(-time.Hour)}), ShouldBeNil)
+
+				Convey("Should only find dashboards with active silences", func() {
+					query := m.GetDashboardsWithSilencedAlertsQuery{OrgId: 1}
+
+					err := GetDashboardsWithSilencedAlerts(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].DashboardId, ShouldEqual, activeDash.Id)
+					So(query.Result[0].SilencedUntil.Unix(), ShouldEqual, until.Unix())
+				})
+
+				Convey("Should not find dashboards after unsilencing", func() {
+					for _, id := range alertIds[activeDash.Id] {
+						So(UnsilenceAlert(&m.UnsilenceAlertCommand{OrgId: 1, AlertId: id}), ShouldBeNil)
+					}
+
+					query := m.GetDashboardsWithSilencedAlertsQuery{OrgId: 1}
+
+					err := GetDashboardsWithSilencedAlerts(&query)
+					So(err, ShouldBeNil)
+					So(len(query.Result), ShouldEqual, 0)
+				})
+			})
+
+			Convey("Given dashboards with tagged annotations", func() {
+				outageDash := insertTestDashboard("outage dash", 1)
+				deployDash := insertTestDashboard("deploy dash", 1)
+
+				repo := SqlAnnotationRepo{}
+				So(repo.Save(&annotations.Item{OrgId: 1, DashboardId: outageDash.Id, Epoch: 10, Tags: []string{"outage", "server:web-1"}}), ShouldBeNil)
+				So(repo.Save(&annotations.Item{OrgId: 1, DashboardId: outageDash.Id, Epoch: 20, Tags: []string{"outage"}}), ShouldBeNil)
+				So(repo.Save(&annotations.Item{OrgId: 1, DashboardId: deployDash.Id, Epoch: 10, Tags: []string{"deploy", "server:web-2"}}), ShouldBeNil)
+
+				Convey("Should find dashboards with the annotation tag once", func() {
+					query := m.GetDashboardsByAnnotationTagQuery{OrgId: 1, Tag: "outage"}
+
+					err := GetDashboardsByAnnotationTag(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].Id, ShouldEqual, outageDash.Id)
+				})
+
+				Convey("Should match key value tags", func() {
+					query := m.GetDashboardsByAnnotationTagQuery{OrgId: 1, Tag: "server:web-2"}
+
+					err := GetDashboardsByAnnotationTag(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].Id, ShouldEqual, deployDash.Id)
+				})
+
+				Convey("Should match tag key regardless of value", func() {
+					query := m.GetDashboardsByAnnotationTagQuery{OrgId: 1, Tag: "server"}
+
+					err := GetDashboardsByAnnotationTag(&query)
+					So(err, ShouldBeNil)
+					So(len(query.Result), ShouldEqual, 2)
+				})
+
+				Convey("Should not find dashboards without the tag", func() {
+					query := m.GetDashboardsByAnnotationTagQuery{OrgId: 1, Tag: "maintenance"}
+
+					err := GetDashboardsByAnnotationTag(&query)
+					So(err, ShouldBeNil)
+					So(len(query.Result), ShouldEqual, 0)
+				})
+			})
+
+			Convey("Given dashboards with panel query timeouts", func() {
+				insertTestDashboardWithData("timeout 30s", 1, map[string]interface{}{
+					"panels": []interface{}{map[string]interface{}{"id": 1, "timeout": "30s"}},
+				})
+				insertTestDashboardWithData("timeout 120s", 1, map[string]interface{}{
+					"panels": []interface{}{
+						map[string]interface{}{"id": 1},
+						map[string]interface{}{"id": 2, "options": map[string]interface{}{"timeout": 120}},
+					},
+				})
+				insertTestDashboardWithData("no timeout", 1, map[string]interface{}{
+					"panels": []interface{}{map[string]interface{}{"id": 1}},
+				})
+
+				Convey("Should find dashboards with long timeouts", func() {
+					query := m.GetDashboardsByQueryTimeoutQuery{OrgId: 1, MinTimeoutSeconds: 60}
+
+					err := GetDashboardsByQueryTimeout(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].Title, ShouldEqual, "timeout 120s")
+				})
+
+				Convey("Should never include dashboards without timeout", func() {
+					query := m.GetDashboardsByQueryTimeoutQuery{OrgId: 1, MinTimeoutSeconds: 0}
+
+					err := GetDashboardsByQueryTimeout(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 2)
+					So(query.Result[0].Title, ShouldEqual, "timeout 120s")
+					So(query.Result[1].Title, ShouldEqual, "timeout 30s")
+				})
+			})
+
+			Convey("Given dashboards with 0, 5 and 20 template variables", func() {
+				withVariables := func(count int) map[string]interface{} {
+					list := make([]interface{}, 0)
+					for i := 0; i < count; i++ {
+						list = append(list, map[string]interface{}{"name": fmt.Sprintf("var%d", i)})
+					}
+					return map[string]interface{}{"templating": map[string]interface{}{"list": list}}
+				}
+
+				insertTestDashboardWithData("0 variables", 1, withVariables(0))
+				insertTestDashboardWithData("5 variables", 1, withVariables(5))
+				insertTestDashboardWithData("20 variables", 1, withVariables(20))
+
+				Convey("Should find dashboards with many variables ordered by count", func() {
+					query := m.GetDashboardsByTemplateVariableCountQuery{OrgId: 1, MinVariables: 5}
+
+					err := GetDashboardsByTemplateVariableCount(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 2)
+					So(query.Result[0].Title, ShouldEqual, "20 variables")
+					So(query.Result[0].VariableCount, ShouldEqual, 20)
+					So(query.Result[1].Title, ShouldEqual, "5 variables")
+					So(query.Result[1].VariableCount, ShouldEqual, 5)
+				})
+
+				Convey("Should include dashboards without variables when min is 0", func() {
+					query := m.GetDashboardsByTemplateVariableCountQuery{OrgId: 1}
+
+					err := GetDashboardsByTemplateVariableCount(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 6)
+					So(query.Result[5].VariableCount, ShouldEqual, 0)
+				})
+			})
+
+			Convey("Given dashboards with alerts evaluated every 10s, 60s and 300s", func() {
+				fastDash := insertTestDashboard("fast alerts", 1)
+				mediumDash := insertTestDashboard("medium alerts", 1)
+				slowDash := insertTestDashboard("slow alerts", 1)
+
+				insertTestAlerts(fastDash,
+					&m.Alert{PanelId: 1, Name: "a", Frequency: 300},
+					&m.Alert{PanelId: 2, Name: "b", Frequency: 10},
+				)
+				insertTestAlerts(mediumDash, &m.Alert{PanelId: 1, Name: "a", Frequency: 60})
+				insertTestAlerts(slowDash, &m.Alert{PanelId: 1, Name: "a", Frequency: 300})
+
+				Convey("Should find dashboards by their most frequent alert", func() {
+					query := m.GetDashboardsByAlertFrequencyQuery{OrgId: 1, MaxFrequencySeconds: 60}
+
+					err := GetDashboardsByAlertFrequency(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 2)
+					So(query.Result[0].DashboardId, ShouldEqual, fastDash.Id)
+					So(query.Result[0].MinFrequency, ShouldEqual, 10)
+					So(query.Result[1].DashboardId, ShouldEqual, mediumDash.Id)
+					So(query.Result[1].MinFrequency, ShouldEqual, 60)
+				})
+
+				Convey("Should only find the fastest dashboard", func() {
+					query := m.GetDashboardsByAlertFrequencyQuery{OrgId: 1, MaxFrequencySeconds: 10}
+
+					err := GetDashboardsByAlertFrequency(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].DashboardId, ShouldEqual, fastDash.Id)
+				})
+			})
+
+			Convey("Given dashboards annotated at different times", func() {
+				now := time.Now()
+				oldDash := insertTestDashboard("annotated last week", 1)
+				hourDash := insertTestDashboard("annotated an hour ago", 1)
+				minuteDash := insertTestDashboard("annotated a minute ago", 1)
+
+				repo := SqlAnnotationRepo{}
+				So(repo.Save(&annotations.Item{OrgId: 1, DashboardId: oldDash.Id, Epoch: now.Add(-7 * 24 * time.Hour).Unix()}), ShouldBeNil)
+				So(repo.Save(&annotations.Item{OrgId: 1, DashboardId: hourDash.Id, Epoch: now.Add(-2 * time.Hour).Unix()}), ShouldBeNil)
+				So(repo.Save(&annotations.Item{OrgId: 1, DashboardId: hourDash.Id, Epoch: now.Add(-time.Hour).Unix()}), ShouldBeNil)
+				So(repo.Save(&annotations.Item{OrgId: 1, DashboardId: minuteDash.Id, Epoch: now.Add(-time.Minute).Unix()}), ShouldBeNil)
+
+				Convey("Should find recently annotated dashboards, most recent first", func() {
+					query := m.GetDashboardsByLastAnnotationQuery{OrgId: 1, Since: now.Add(-24 * time.Hour)}
+
+					err := GetDashboardsByLastAnnotation(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 2)
+					So(query.Result[0].DashboardId, ShouldEqual, minuteDash.Id)
+					So(query.Result[1].DashboardId, ShouldEqual, hourDash.Id)
+					So(query.Result[1].LastAnnotationEpoch, ShouldEqual, now.Add(-time.Hour).Unix())
+				})
+			})
+
+			Convey("Should be able to get paged dashboards with their tags", func() {
+				insertTestDashboard("test dash 89", 1)
+				insertTestDashboard("test dash 90", 1, "staging", "api", "prod")
+
+				query := m.GetDashboardsByOrgWithTagSummaryQuery{OrgId: 1, Page: 1, Limit: 3}
+
+				err := GetDashboardsByOrgWithTagSummary(&query)
+				So(err, ShouldBeNil)
+
+				So(len(query.Result), ShouldEqual, 3)
+				So(query.Result[0].Title, ShouldEqual, "test dash 23")
+				So(query.Result[0].Tags, ShouldResemble, []string{"prod", "webapp"})
+				So(query.Result[1].Title, ShouldEqual, "test dash 45")
+				So(query.Result[1].Tags, ShouldResemble, []string{"prod"})
+
+				Convey("and the next page", func() {
+					query := m.GetDashboardsByOrgWithTagSummaryQuery{OrgId: 1, Page: 2, Limit: 3}
+
+					err := GetDashboardsByOrgWithTagSummary(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 2)
+					So(query.Result[0].Title, ShouldEqual, "test dash 89")
+					So(query.Result[0].Tags, ShouldResemble, []string{})
+					So(query.Result[1].Title, ShouldEqual, "test dash 90")
+					So(query.Result[1].Tags, ShouldResemble, []string{"api", "prod", "staging"})
+				})
+			})
+
+			Convey("Given dashboards referring to existing and deleted datasources", func() {
+				So(AddDataSource(&m.AddDataSourceCommand{OrgId: 1, Name: "prom", Type: "prometheus", Access: m.DS_ACCESS_PROXY}), ShouldBeNil)
+
+				withPanels := func(panels ...interface{}) map[string]interface{} {
+					return map[string]interface{}{"panels": panels}
+				}
+
+				insertTestDashboardWithData("healthy", 1, withPanels(
+					map[string]interface{}{"id": 1, "datasource": "prom"},
+					map[string]interface{}{"id": 2, "datasource": "-- Grafana --"},
+					map[string]interface{}{"id": 3, "datasource": "$ds"},
+				))
+				insertTestDashboardWithData("broken", 1, withPanels(
+					map[string]interface{}{"id": 1, "datasource": "prom"},
+					map[string]interface{}{"id": 2, "datasource": "-- Mixed --", "targets": []interface{}{
+						map[string]interface{}{"datasource": "deleted"},
+					}},
+				))
+				insertTestDashboardWithData("default datasource", 1, withPanels(
+					map[string]interface{}{"id": 1, "datasource": nil},
+				))
+
+				Convey("Should find dashboards with missing datasources", func() {
+					query := m.GetDashboardsByNonExistentDatasourceQuery{OrgId: 1}
+
+					err := GetDashboardsByNonExistentDatasource(&query)
+					So(err, ShouldBeNil)
+
+					// there is no default datasource in the org
+					So(len(query.Result), ShouldEqual, 2)
+					So(query.Result[0].Title, ShouldEqual, "broken")
+					So(query.Result[0].MissingDatasources, ShouldResemble, []string{"deleted"})
+					So(query.Result[1].Title, ShouldEqual, "default datasource")
+					So(query.Result[1].MissingDatasources, ShouldResemble, []string{""})
+				})
+			})
+
+			Convey("Given dashboard versions with messages", func() {
+				saveTestDashboardVersion(savedDash, "Build 1234: add latency panel")
+				saveTestDashboardVersion(savedDash, "fix typo")
+				secondDash := insertTestDashboard("test dash 45b", 1)
+				saveTestDashboardVersion(secondDash, "build 1235: remove latency panel")
+
+				Convey("Should find partial matches across dashboards", func() {
+					query := m.GetDashboardsByVersionMessageKeywordQuery{OrgId: 1, Keyword: "latency"}
+
+					err := GetDashboardsByVersionMessageKeyword(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 2)
+					So(query.Result[0].DashboardId, ShouldEqual, savedDash.Id)
+					So(query.Result[0].DashboardTitle, ShouldEqual, "test dash 23")
+					So(query.Result[0].Version, ShouldEqual, 2)
+					So(query.Result[1].DashboardId, ShouldEqual, secondDash.Id)
+				})
+
+				Convey("Should find exact matches", func() {
+					query := m.GetDashboardsByVersionMessageKeywordQuery{OrgId: 1, Keyword: "fix typo"}
+
+					err := GetDashboardsByVersionMessageKeyword(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].Version, ShouldEqual, 3)
+					So(query.Result[0].Message, ShouldEqual, "fix typo")
+				})
+
+				Convey("Should match case insensitive", func() {
+					query := m.GetDashboardsByVersionMessageKeywordQuery{OrgId: 1, Keyword: "BUILD 123"}
+
+					err := GetDashboardsByVersionMessageKeyword(&query)
+					So(err, ShouldBeNil)
+					So(len(query.Result), ShouldEqual, 2)
+				})
+
+				Convey("Should not find versions in another org", func() {
+					query := m.GetDashboardsByVersionMessageKeywordQuery{OrgId: 2, Keyword: "build"}
+
+					err := GetDashboardsByVersionMessageKeyword(&query)
+					So(err, ShouldBeNil)
+					So(len(query.Result), ShouldEqual, 0)
+				})
+			})
+
+			Convey("Given dashboards with alerts in each state", func() {
+				mixedDash := insertTestDashboard("mixed states", 1)
+				okDash := insertTestDashboard("all ok", 1)
+
+				insertTestAlerts(mixedDash,
+					&m.Alert{PanelId: 1, Name: "a"},
+					&m.Alert{PanelId: 2, Name: "b"},
+					&m.Alert{PanelId: 3, Name: "c"},
+					&m.Alert{PanelId: 4, Name: "d"},
+					&m.Alert{PanelId: 5, Name: "e"},
+				)
+				insertTestAlerts(okDash, &m.Alert{PanelId: 1, Name: "a"})
+
+				setTestAlertStates(mixedDash, m.AlertStateAlerting, m.AlertStateAlerting, m.AlertStateOK, m.AlertStateNoData, m.AlertStatePending)
+				setTestAlertStates(okDash, m.AlertStateOK)
+
+				Convey("Should count alerts per state", func() {
+					query := m.GetDashboardsByOrgWithAlertStatusQuery{OrgId: 1}
+
+					err := GetDashboardsByOrgWithAlertStatus(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 2)
+
+					So(query.Result[0].DashboardId, ShouldEqual, okDash.Id)
+					So(query.Result[0].OKCount, ShouldEqual, 1)
+					So(query.Result[0].AlertingCount, ShouldEqual, 0)
+
+					So(query.Result[1].DashboardId, ShouldEqual, mixedDash.Id)
+					So(query.Result[1].AlertingCount, ShouldEqual, 2)
+					So(query.Result[1].OKCount, ShouldEqual, 1)
+					So(query.Result[1].NoDataCount, ShouldEqual, 1)
+					So(query.Result[1].PendingCount, ShouldEqual, 1)
+				})
+			})
+
+			Convey("Given dashboards with 0, 10 and 100 panels", func() {
+				withPanels := func(count int) map[string]interface{} {
+					panels := make([]interface{}, 0)
+					for i := 0; i < count; i++ {
+						panels = append(panels, map[string]interface{}{"id": i + 1, "type": "graph"})
+					}
+					return map[string]interface{}{"panels": panels}
+				}
+
+				insertTestDashboardWithData("0 panels", 1, withPanels(0))
+				insertTestDashboardWithData("10 panels", 1, withPanels(10))
+				insertTestDashboardWithData("100 panels", 1, withPanels(100))
+
+				Convey("Should find dashboards with many panels ordered by count", func() {
+					query := m.GetDashboardsByPanelCountQuery{OrgId: 1, MinPanels: 10}
+
+					err := GetDashboardsByPanelCount(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 2)
+					So(query.Result[0].Title, ShouldEqual, "100 panels")
+					So(query.Result[0].PanelCount, ShouldEqual, 100)
+					So(query.Result[1].Title, ShouldEqual, "10 panels")
+					So(query.Result[1].PanelCount, ShouldEqual, 10)
+				})
+
+				Convey("Should respect max panels", func() {
+					query := m.GetDashboardsByPanelCountQuery{OrgId: 1, MinPanels: 1, MaxPanels: 50}
+
+					err := GetDashboardsByPanelCount(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].Title, ShouldEqual, "10 panels")
+				})
+			})
+
+			Convey("Given dashboards with different refresh values", func() {
+				insertTestDashboardWithData("refresh 5s", 1, map[string]interface{}{"refresh": "5s"})
+				insertTestDashboardWithData("refresh 5s again", 1, map[string]interface{}{"refresh": "5s"})
+				insertTestDashboardWithData("refresh 1m", 1, map[string]interface{}{"refresh": "1m"})
+				insertTestDashboardWithData("refresh off", 1, map[string]interface{}{"refresh": ""})
+				insertTestDashboardWithData("refresh null", 1, map[string]interface{}{"refresh": nil})
+				insertTestDashboardWithData("other org refresh", 2, map[string]interface{}{"refresh": "1m"})
+
+				Convey("Should count dashboards per refresh value", func() {
+					query := m.GetDashboardsByRefreshIntervalQuery{OrgId: 1}
+
+					err := GetDashboardsByRefreshInterval(&query)
+					So(err, ShouldBeNil)
+
+					So(query.Result["5s"], ShouldEqual, 2)
+					So(query.Result["1m"], ShouldEqual, 1)
+					// refresh off, null and the three dashboards without refresh
+					So(query.Result[""], ShouldEqual, 5)
+					So(len(query.Result), ShouldEqual, 3)
+				})
+			})
+
+			Convey("Given dashboards with panels having 1, 3 and 10 targets", func() {
+				withTargets := func(counts ...int) map[string]interface{} {
+					panels := make([]interface{}, 0)
+					for i, count := range counts {
+						targets := make([]interface{}, 0)
+						for j := 0; j < count; j++ {
+							targets = append(targets, map[string]interface{}{"refId": fmt.Sprintf("%c", 'A'+j)})
+						}
+						panels = append(panels, map[string]interface{}{"id": i + 1, "targets": targets})
+					}
+					return map[string]interface{}{"panels": panels}
+				}
+
+				insertTestDashboardWithData("1 target", 1, withTargets(1))
+				insertTestDashboardWithData("3 targets", 1, withTargets(3))
+				insertTestDashboardWithData("10 and 3 targets", 1, withTargets(10, 3))
+
+				Convey("Should find dashboards with many targets ordered by count", func() {
+					query := m.GetDashboardsByQueryTargetCountQuery{OrgId: 1, MinTargets: 3}
+
+					err := GetDashboardsByQueryTargetCount(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 2)
+					So(query.Result[0].Title, ShouldEqual, "10 and 3 targets")
+					So(query.Result[0].TargetCount, ShouldEqual, 13)
+					So(query.Result[0].MaxPanelTarget, ShouldEqual, 10)
+					So(query.Result[1].Title, ShouldEqual, "3 targets")
+					So(query.Result[1].TargetCount, ShouldEqual, 3)
+				})
+			})
+
+			Convey("Given dashboards with 0, 10 and 500 annotations", func() {
+				insertTestDashboard("no annotations", 1)
+				tenDash := insertTestDashboard("10 annotations", 1)
+				manyDash := insertTestDashboard("500 annotations", 1)
+
+				repo := SqlAnnotationRepo{}
+				for i := 0; i < 500; i++ {
+					if i < 10 {
+						So(repo.Save(&annotations.Item{OrgId: 1, DashboardId: tenDash.Id, Epoch: int64(i)}), ShouldBeNil)
+					}
+					So(repo.Save(&annotations.Item{OrgId: 1, DashboardId: manyDash.Id, Epoch: int64(i)}), ShouldBeNil)
+				}
+
+				Convey("Should find dashboards with many annotations ordered by count", func() {
+					query := m.GetDashboardsByAnnotationCountQuery{OrgId: 1, MinAnnotations: 10}
+
+					err := GetDashboardsByAnnotationCount(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 2)
+					So(query.Result[0].DashboardId, ShouldEqual, manyDash.Id)
+					So(query.Result[0].AnnotationCount, ShouldEqual, 500)
+					So(query.Result[1].DashboardId, ShouldEqual, tenDash.Id)
+					So(query.Result[1].AnnotationCount, ShouldEqual, 10)
+				})
+
+				Convey("Should include dashboards without annotations when min is 0", func() {
+					query := m.GetDashboardsByAnnotationCountQuery{OrgId: 1}
+
+					err := GetDashboardsByAnnotationCount(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 6)
+					So(query.Result[5].AnnotationCount, ShouldEqual, 0)
+				})
+			})
+
+			Convey("Given dashboards with 0, 5 and 20 rows", func() {
+				withRows := func(count int) map[string]interface{} {
+					panels := make([]interface{}, 0)
+					for i := 0; i < count; i++ {
+						panels = append(panels,
+							map[string]interface{}{"id": i*2 + 1, "type": "row"},
+							map[string]interface{}{"id": i*2 + 2, "type": "graph"},
+						)
+					}
+					return map[string]interface{}{"panels": panels}
+				}
+
+				insertTestDashboardWithData("0 rows", 1, withRows(0))
+				insertTestDashboardWithData("5 rows", 1, withRows(5))
+				insertTestDashboardWithData("20 rows", 1, withRows(20))
+				insertTestDashboardWithData("5 legacy rows", 1, map[string]interface{}{
+					"rows": []interface{}{
+						map[string]interface{}{}, map[string]interface{}{}, map[string]interface{}{},
+						map[string]interface{}{}, map[string]interface{}{},
+					},
+				})
+
+				Convey("Should find dashboards with many rows ordered by count", func() {
+					query := m.GetDashboardsByRowCountQuery{OrgId: 1, MinRows: 5}
+
+					err := GetDashboardsByRowCount(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 3)
+					So(query.Result[0].Title, ShouldEqual, "20 rows")
+					So(query.Result[0].RowCount, ShouldEqual, 20)
+					So(query.Result[1].Title, ShouldEqual, "5 legacy rows")
+					So(query.Result[1].RowCount, ShouldEqual, 5)
+					So(query.Result[2].Title, ShouldEqual, "5 rows")
+					So(query.Result[2].RowCount, ShouldEqual, 5)
+				})
+			})
+
+			Convey("Given one annotated dashboard", func() {
+				annotatedDash := insertTestDashboard("annotated", 1)
+
+				repo := SqlAnnotationRepo{}
+				So(repo.Save(&annotations.Item{OrgId: 1, DashboardId: annotatedDash.Id, Epoch: 1}), ShouldBeNil)
+				So(repo.Save(&annotations.Item{OrgId: 1, DashboardId: annotatedDash.Id, Epoch: 2}), ShouldBeNil)
+
+				Convey("Should only find the annotated dashboard once", func() {
+					query := m.GetDashboardsByHasAnnotationsQuery{OrgId: 1, HasAnnotations: true}
+
+					err := GetDashboardsByHasAnnotations(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].Id, ShouldEqual, annotatedDash.Id)
+				})
+
+				Convey("Should find dashboards without annotations", func() {
+					query := m.GetDashboardsByHasAnnotationsQuery{OrgId: 1, HasAnnotations: false}
+
+					err := GetDashboardsByHasAnnotations(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 3)
+					for _, dash := range query.Result {
+						So(dash.Id, ShouldNotEqual, annotatedDash.Id)
+					}
+				})
+			})
+
+			Convey("Given annotations of different ages", func() {
+				now := time.Now()
+				dash := insertTestDashboard("test dash 00", 1)
+
+				repo := SqlAnnotationRepo{}
+				So(repo.Save(&annotations.Item{OrgId: 1, DashboardId: dash.Id, Epoch: now.Add(-30 * 24 * time.Hour).Unix()}), ShouldBeNil)
+				So(repo.Save(&annotations.Item{OrgId: 1, DashboardId: dash.Id, Epoch: now.Add(-2 * time.Hour).Unix()}), ShouldBeNil)
+				So(repo.Save(&annotations.Item{OrgId: 1, DashboardId: dash.Id, Epoch: now.Add(-time.Minute).Unix()}), ShouldBeNil)
+				So(repo.Save(&annotations.Item{OrgId: 1, DashboardId: savedDash.Id, Epoch: now.Add(-7 * 24 * time.Hour).Unix()}), ShouldBeNil)
+
+				Convey("Should only count recent annotations", func() {
+					query := m.GetDashboardsByOrgWithAnnotationSummaryQuery{OrgId: 1, Since: now.Add(-24 * time.Hour), Page: 1, Limit: 2}
+
+					err := GetDashboardsByOrgWithAnnotationSummary(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 2)
+					So(query.Result[0].DashboardId, ShouldEqual, dash.Id)
+					So(query.Result[0].AnnotationCount, ShouldEqual, 2)
+					So(query.Result[1].Title, ShouldEqual, "test dash 23")
+					So(query.Result[1].AnnotationCount, ShouldEqual, 0)
+				})
+
+				Convey("Should count older annotations with an earlier since", func() {
+					query := m.GetDashboardsByOrgWithAnnotationSummaryQuery{OrgId: 1, Since: now.Add(-8 * 24 * time.Hour), Page: 1, Limit: 2}
+
+					err := GetDashboardsByOrgWithAnnotationSummary(&query)
+					So(err, ShouldBeNil)
+
+					So(query.Result[1].DashboardId, ShouldEqual, savedDash.Id)
+					So(query.Result[1].AnnotationCount, ShouldEqual, 1)
+				})
+
+				Convey("Should return the next page", func() {
+					query := m.GetDashboardsByOrgWithAnnotationSummaryQuery{OrgId: 1, Since: now.Add(-24 * time.Hour), Page: 2, Limit: 2}
+
+					err := GetDashboardsByOrgWithAnnotationSummary(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 2)
+					So(query.Result[0].Title, ShouldEqual, "test dash 45")
+					So(query.Result[1].Title, ShouldEqual, "test dash 67")
+				})
+			})
+
+			Convey("Given dashboards restored 0, 1 and 5 times", func() {
+				restore := func(dash *m.Dashboard, times int) {
+					for i := 0; i < times; i++ {
+						cmd := m.SaveDashboardCommand{
+							OrgId:        dash.OrgId,
+							Overwrite:    true,
+							RestoredFrom: 1,
+							Dashboard: simplejson.NewFromAny(map[string]interface{}{
+								"id":    dash.Id,
+								"title": dash.Title,
+							}),
+						}
+						So(SaveDashboard(&cmd), ShouldBeNil)
+					}
+				}
+
+				neverDash := insertTestDashboard("never restored", 1)
+				onceDash := insertTestDashboard("restored once", 1)
+				oftenDash := insertTestDashboard("restored 5 times", 1)
+
+				saveTestDashboardVersion(neverDash, "plain update")
+				restore(onceDash, 1)
+				restore(oftenDash, 5)
+
+				Convey("Should find restored dashboards ordered by restore count", func() {
+					query := m.GetDashboardsByVersionRestoreCountQuery{OrgId: 1, MinRestores: 1}
+
+					err := GetDashboardsByVersionRestoreCount(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 2)
+					So(query.Result[0].DashboardId, ShouldEqual, oftenDash.Id)
+					So(query.Result[0].RestoreCount, ShouldEqual, 5)
+					So(query.Result[1].DashboardId, ShouldEqual, onceDash.Id)
+					So(query.Result[1].RestoreCount, ShouldEqual, 1)
+				})
+
+				Convey("Should only find the dashboard restored often", func() {
+					query := m.GetDashboardsByVersionRestoreCountQuery{OrgId: 1, MinRestores: 2}
+
+					err := GetDashboardsByVersionRestoreCount(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].DashboardId, ShouldEqual, oftenDash.Id)
+				})
+			})
+
+			Convey("Given a dashboard imported by a plugin", func() {
+				cmd := m.SaveDashboardCommand{
+					OrgId:    1,
+					PluginId: "test-app",
+					Dashboard: simplejson.NewFromAny(map[string]interface{}{
+						"id":    nil,
+						"title": "plugin dash",
+					}),
+				}
+				So(SaveDashboard(&cmd), ShouldBeNil)
+				pluginDash := cmd.Result
+
+				Convey("Should only find the plugin dashboard", func() {
+					query := m.GetDashboardsByHasPluginIdQuery{OrgId: 1, HasPluginId: true}
+
+					err := GetDashboardsByHasPluginId(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].Id, ShouldEqual, pluginDash.Id)
+					So(query.Result[0].PluginId, ShouldEqual, "test-app")
+				})
+
+				Convey("Should only find hand-crafted dashboards", func() {
+					query := m.GetDashboardsByHasPluginIdQuery{OrgId: 1, HasPluginId: false}
+
+					err := GetDashboardsByHasPluginId(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 3)
+					for _, dash := range query.Result {
+						So(dash.Id, ShouldNotEqual, pluginDash.Id)
+					}
+				})
+			})
+
+			Convey("Given dashboards with versions and stars", func() {
+				popularDash := insertTestDashboard("popular dash", 1, "team-a", "prod")
+				saveTestDashboardVersion(popularDash, "second version")
+				saveTestDashboardVersion(popularDash, "third version")
+
+				for _, userId := range []int64{1, 2, 3} {
+					So(StarDashboard(&m.StarDashboardCommand{DashboardId: popularDash.Id, UserId: userId}), ShouldBeNil)
+				}
+				So(StarDashboard(&m.StarDashboardCommand{DashboardId: savedDash.Id, UserId: 1}), ShouldBeNil)
+
+				Convey("Should return a page of dashboard summaries", func() {
+					query := m.GetDashboardsByOrgWithFolderAndTagSummaryQuery{OrgId: 1, Page: 1, Limit: 2}
+
+					err := GetDashboardsByOrgWithFolderAndTagSummary(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 2)
+
+					So(query.Result[0].DashboardId, ShouldEqual, popularDash.Id)
+					So(query.Result[0].Tags, ShouldResemble, []string{"prod", "team-a"})
+					So(query.Result[0].Version, ShouldEqual, 3)
+					So(query.Result[0].VersionCount, ShouldEqual, 3)
+					So(query.Result[0].StarCount, ShouldEqual, 3)
+
+					So(query.Result[1].DashboardId, ShouldEqual, savedDash.Id)
+					So(query.Result[1].Tags, ShouldResemble, []string{"prod", "webapp"})
+					So(query.Result[1].VersionCount, ShouldEqual, 1)
+					So(query.Result[1].StarCount, ShouldEqual, 1)
+				})
+
+				Convey("Should return the next page", func() {
+					query := m.GetDashboardsByOrgWithFolderAndTagSummaryQuery{OrgId: 1, Page: 2, Limit: 2}
+
+					err := GetDashboardsByOrgWithFolderAndTagSummary(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 2)
+					So(query.Result[0].Title, ShouldEqual, "test dash 45")
+					So(query.Result[0].StarCount, ShouldEqual, 0)
+					So(query.Result[1].Title, ShouldEqual, "test dash 67")
+				})
+			})
+
+			Convey("Given dashboards with 0 and 3 no data alerts", func() {
+				healthyDash := insertTestDashboard("healthy alerts", 1)
+				noDataDash := insertTestDashboard("no data alerts", 1)
+
+				insertTestAlerts(healthyDash,
+					&m.Alert{PanelId: 1, Name: "a"},
+					&m.Alert{PanelId: 2, Name: "b"},
+				)
+				insertTestAlerts(noDataDash,
+					&m.Alert{PanelId: 1, Name: "a"},
+					&m.Alert{PanelId: 2, Name: "b"},
+					&m.Alert{PanelId: 3, Name: "c"},
+					&m.Alert{PanelId: 4, Name: "d"},
+				)
+
+				setTestAlertStates(healthyDash, m.AlertStateOK, m.AlertStateAlerting)
+				setTestAlertStates(noDataDash, m.AlertStateNoData, m.AlertStateOK, m.AlertStateNoData, m.AlertStateNoData)
+
+				Convey("Should only find the dashboard with no data alerts", func() {
+					query := m.GetDashboardsByNoDataAlertCountQuery{OrgId: 1, MinNoDataAlerts: 1}
+
+					err := GetDashboardsByNoDataAlertCount(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].DashboardId, ShouldEqual, noDataDash.Id)
+					So(query.Result[0].NoDataCount, ShouldEqual, 3)
+				})
+
+				Convey("Should include dashboards without no data alerts when min is 0", func() {
+					query := m.GetDashboardsByNoDataAlertCountQuery{OrgId: 1}
+
+					err := GetDashboardsByNoDataAlertCount(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 2)
+					So(query.Result[1].DashboardId, ShouldEqual, healthyDash.Id)
+					So(query.Result[1].NoDataCount, ShouldEqual, 0)
+				})
+			})
+
+			Convey("Given dashboards with 2, 5 and 11 queries", func() {
+				withQueries := func(count int) map[string]interface{} {
+					targets := make([]interface{}, 0)
+					for i := 0; i < count; i++ {
+						targets = append(targets, map[string]interface{}{"refId": fmt.Sprintf("%c", 'A'+i)})
+					}
+					return map[string]interface{}{
+						"panels": []interface{}{map[string]interface{}{"id": 1, "targets": targets}},
+					}
+				}
+
+				insertTestDashboardWithData("2 queries", 1, withQueries(2))
+				insertTestDashboardWithData("5 queries", 1, withQueries(5))
+				insertTestDashboardWithData("11 queries", 1, withQueries(11))
+
+				Convey("Should include dashboards at the boundaries", func() {
+					query := m.GetDashboardsByQueryCountRangeQuery{OrgId: 1, MinQueries: 2, MaxQueries: 10}
+
+					err := GetDashboardsByQueryCountRange(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 2)
+					So(query.Result[0].Title, ShouldEqual, "2 queries")
+					So(query.Result[0].QueryCount, ShouldEqual, 2)
+					So(query.Result[1].Title, ShouldEqual, "5 queries")
+					So(query.Result[1].QueryCount, ShouldEqual, 5)
+				})
+
+				Convey("Should find dashboards without queries when min is 0", func() {
+					query := m.GetDashboardsByQueryCountRangeQuery{OrgId: 1, MinQueries: 0, MaxQueries: 1}
+
+					err := GetDashboardsByQueryCountRange(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 3)
+					So(query.Result[0].QueryCount, ShouldEqual, 0)
+				})
+			})
+
+			Convey("Given a dashboard created and updated by different users", func() {
+				creatorCmd := m.CreateUserCommand{Login: "creator", Email: "creator@test.com"}
+				updaterCmd := m.CreateUserCommand{Login: "updater", Email: "updater@test.com"}
+				So(CreateUser(&creatorCmd), ShouldBeNil)
+				So(CreateUser(&updaterCmd), ShouldBeNil)
+
+				createCmd := m.SaveDashboardCommand{
+					OrgId:     1,
+					UserId:    creatorCmd.Result.Id,
+					Dashboard: simplejson.NewFromAny(map[string]interface{}{"id": nil, "title": "test dash 00"}),
+				}
+				So(SaveDashboard(&createCmd), ShouldBeNil)
+
+				updateCmd := m.SaveDashboardCommand{
+					OrgId:     1,
+					UserId:    updaterCmd.Result.Id,
+					Overwrite: true,
+					Dashboard: simplejson.NewFromAny(map[string]interface{}{
+						"id":      createCmd.Result.Id,
+						"title":   "test dash 00",
+						"version": createCmd.Result.Version,
+					}),
+				}
+				So(SaveDashboard(&updateCmd), ShouldBeNil)
+
+				Convey("Should return the creator and updater details", func() {
+					query := m.GetDashboardsByOrgWithUserInfoQuery{OrgId: 1, Page: 1, Limit: 2}
+
+					err := GetDashboardsByOrgWithUserInfo(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 2)
+					So(query.Result[0].DashboardId, ShouldEqual, createCmd.Result.Id)
+					So(query.Result[0].CreatedByLogin, ShouldEqual, "creator")
+					So(query.Result[0].CreatedByEmail, ShouldEqual, "creator@test.com")
+					So(query.Result[0].UpdatedByLogin, ShouldEqual, "updater")
+					So(query.Result[0].UpdatedByEmail, ShouldEqual, "updater@test.com")
+
+					So(query.Result[1].Title, ShouldEqual, "test dash 23")
+					So(query.Result[1].CreatedByLogin, ShouldEqual, "")
+				})
+			})
+
+			Convey("Given starred and tagged dashboards", func() {
+				starredTaggedDash := insertTestDashboard("starred and tagged", 1, "team-a")
+				starredDash := insertTestDashboard("starred only", 1, "team-b")
+				insertTestDashboard("tagged only", 1, "team-a")
+
+				So(StarDashboard(&m.StarDashboardCommand{DashboardId: starredTaggedDash.Id, UserId: 10}), ShouldBeNil)
+				So(StarDashboard(&m.StarDashboardCommand{DashboardId: starredDash.Id, UserId: 10}), ShouldBeNil)
+
+				Convey("Should only find dashboards that are both starred and tagged", func() {
+					query := m.GetDashboardsByStarredAndTagQuery{OrgId: 1, UserId: 10, Tag: "team-a"}
+
+					err := GetDashboardsByStarredAndTag(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].Id, ShouldEqual, starredTaggedDash.Id)
+				})
+
+				Convey("Should not find dashboards starred by another user", func() {
+					query := m.GetDashboardsByStarredAndTagQuery{OrgId: 1, UserId: 1, Tag: "team-a"}
+
+					err := GetDashboardsByStarredAndTag(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 0)
+				})
+			})
+
+			Convey("Given dashboards of 1 KB, 100 KB and 1 MB", func() {
+				withSize := func(size int) map[string]interface{} {
+					return map[string]interface{}{"description": strings.Repeat("x", size)}
+				}
+
+				smallDash := insertTestDashboardWithData("1 KB", 1, withSize(1024))
+				mediumDash := insertTestDashboardWithData("100 KB", 1, withSize(100*1024))
+				insertTestDashboardWithData("1 MB", 1, withSize(1024*1024))
+
+				Convey("Should only find dashboards below the max size, largest first", func() {
+					query := m.GetDashboardsByOrgAndMaxSizeQuery{OrgId: 1, MaxBytes: 200 * 1024}
+
+					err := GetDashboardsByOrgAndMaxSize(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 5)
+					So(query.Result[0].DashboardId, ShouldEqual, mediumDash.Id)
+					So(query.Result[0].Size, ShouldBeGreaterThan, 100*1024)
+					So(query.Result[1].DashboardId, ShouldEqual, smallDash.Id)
+					So(query.Result[1].Size, ShouldBeGreaterThan, 1024)
+				})
+
+				Convey("Should exclude dashboards above the max size", func() {
+					query := m.GetDashboardsByOrgAndMaxSizeQuery{OrgId: 1, MaxBytes: 1000}
+
+					err := GetDashboardsByOrgAndMaxSize(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 3)
+					for _, item := range query.Result {
+						So(item.Size, ShouldBeLessThanOrEqualTo, 1000)
+					}
+				})
+
+				Convey("Should count multi byte characters in bytes", func() {
+					// 512 characters taking 1 KB
+					wideDash := insertTestDashboardWithData("wide chars", 1, map[string]interface{}{"description": strings.Repeat("é", 512)})
+
+					query := m.GetDashboardsByOrgAndMaxSizeQuery{OrgId: 1, MaxBytes: 1000}
+
+					err := GetDashboardsByOrgAndMaxSize(&query)
+					So(err, ShouldBeNil)
+
+					for _, item := range query.Result {
+						So(item.DashboardId, ShouldNotEqual, wideDash.Id)
+					}
+				})
+			})
+
+			Convey("Given dashboards imported by a plugin", func() {
+				for _, title := range []string{"plugin dash 1", "plugin dash 2"} {
+					cmd := m.SaveDashboardCommand{
+						OrgId:     1,
+						PluginId:  "test-app",
+						Dashboard: simplejson.NewFromAny(map[string]interface{}{"id": nil, "title": title}),
+					}
+					So(SaveDashboard(&cmd), ShouldBeNil)
+				}
+
+				Convey("Should only list user created dashboards", func() {
+					query := m.GetDashboardsByOrgExcludingPluginDashboardsQuery{OrgId: 1}
+
+					err := GetDashboardsByOrgExcludingPluginDashboards(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 3)
+					for _, dash := range query.Result {
+						So(dash.PluginId, ShouldEqual, "")
+					}
+				})
+
+				Convey("Should page user created dashboards", func() {
+					query := m.GetDashboardsByOrgExcludingPluginDashboardsQuery{OrgId: 1, Page: 2, Limit: 2}
+
+					err := GetDashboardsByOrgExcludingPluginDashboards(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].Title, ShouldEqual, "test dash 67")
+				})
+			})
+
+			Convey("Given alerts pending for different durations", func() {
+				stuckDash := insertTestDashboard("stuck alerts", 1)
+				recentDash := insertTestDashboard("recently pending alerts", 1)
+
+				insertTestAlerts(stuckDash,
+					&m.Alert{PanelId: 1, Name: "a"},
+					&m.Alert{PanelId: 2, Name: "b"},
+					&m.Alert{PanelId: 3, Name: "never evaluated"},
+				)
+				insertTestAlerts(recentDash, &m.Alert{PanelId: 1, Name: "a"})
+
+				setPendingSince := func(dash *m.Dashboard, name string, since time.Time) {
+					alert := m.Alert{}
+					has, err := x.Where("dashboard_id=? AND name=?", dash.Id, name).Get(&alert)
+					So(err, ShouldBeNil)
+					So(has, ShouldBeTrue)
+
+					So(SetAlertState(&m.SetAlertStateCommand{AlertId: alert.Id, OrgId: 1, State: m.AlertStateOK}), ShouldBeNil)
+					So(SetAlertState(&m.SetAlertStateCommand{AlertId: alert.Id, OrgId: 1, State: m.AlertStatePending}), ShouldBeNil)
+
+					_, err = x.Exec("UPDATE alert SET new_state_date = ? WHERE id = ?", since, alert.Id)
+					So(err, ShouldBeNil)
+				}
+
+				now := time.Now()
+				setPendingSince(stuckDash, "a", now.Add(-2*time.Hour))
+				setPendingSince(stuckDash, "b", now.Add(-3*time.Hour))
+				setPendingSince(recentDash, "a", now.Add(-time.Minute))
+
+				_, err := x.Exec("UPDATE alert SET new_state_date = ? WHERE dashboard_id = ? AND name = ?", now.Add(-24*time.Hour), stuckDash.Id, "never evaluated")
+				So(err, ShouldBeNil)
+
+				Convey("Should find dashboards with alerts stuck in pending", func() {
+					query := m.GetDashboardsByAlertPendingDurationQuery{OrgId: 1, PendingForMoreThan: time.Hour}
+
+					err := GetDashboardsByAlertPendingDuration(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].DashboardId, ShouldEqual, stuckDash.Id)
+					So(query.Result[0].PendingCount, ShouldEqual, 2)
+				})
+
+				Convey("Should include recently pending alerts with a short duration", func() {
+					query := m.GetDashboardsByAlertPendingDurationQuery{OrgId: 1, PendingForMoreThan: 30 * time.Second}
+
+					err := GetDashboardsByAlertPendingDuration(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 2)
+					So(query.Result[1].DashboardId, ShouldEqual, recentDash.Id)
+				})
+			})
+
+			Convey("Given dashboards last updated at different dates", func() {
+				cutoff := time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC)
+
+				setUpdated := func(dash *m.Dashboard, updated time.Time) {
+					_, err := x.Exec("UPDATE dashboard SET updated = ? WHERE id = ?", updated, dash.Id)
+					So(err, ShouldBeNil)
+				}
+
+				oldestDash := insertTestDashboard("updated last year", 1)
+				olderDash := insertTestDashboard("updated last month", 1)
+				boundaryDash := insertTestDashboard("updated at cutoff", 1)
+
+				setUpdated(oldestDash, cutoff.AddDate(-1, 0, 0))
+				setUpdated(olderDash, cutoff.AddDate(0, -1, 0))
+				setUpdated(boundaryDash, cutoff)
+
+				Convey("Should only find dashboards updated before the date", func() {
+					query := m.GetDashboardsByOrgAndUpdatedBeforeDateQuery{OrgId: 1, UpdatedBefore: cutoff}
+
+					err := GetDashboardsByOrgAndUpdatedBeforeDate(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 2)
+					So(query.Result[0].Id, ShouldEqual, oldestDash.Id)
+					So(query.Result[1].Id, ShouldEqual, olderDash.Id)
+				})
+
+				Convey("Should page the results", func() {
+					query := m.GetDashboardsByOrgAndUpdatedBeforeDateQuery{OrgId: 1, UpdatedBefore: cutoff, Page: 2, Limit: 1}
+
+					err := GetDashboardsByOrgAndUpdatedBeforeDate(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].Id, ShouldEqual, olderDash.Id)
+				})
+			})
+
+			Convey("Given alerts that changed state at different times", func() {
+				busyDash := insertTestDashboard("busy alerts", 1)
+				quietDash := insertTestDashboard("quiet alerts", 1)
+
+				insertTestAlerts(busyDash,
+					&m.Alert{PanelId: 1, Name: "a"},
+					&m.Alert{PanelId: 2, Name: "b"},
+					&m.Alert{PanelId: 3, Name: "c"},
+				)
+				insertTestAlerts(quietDash, &m.Alert{PanelId: 1, Name: "a"})
+
+				now := time.Now().Truncate(time.Second)
+				setStateDate := func(dash *m.Dashboard, name string, date time.Time) {
+					_, err := x.Exec("UPDATE alert SET new_state_date = ? WHERE dashboard_id = ? AND name = ?", date, dash.Id, name)
+					So(err, ShouldBeNil)
+				}
+
+				setStateDate(busyDash, "a", now.Add(-10*time.Minute))
+				setStateDate(busyDash, "b", now.Add(-time.Minute))
+				setStateDate(busyDash, "c", now.Add(-2*24*time.Hour))
+				setStateDate(quietDash, "a", now.Add(-2*time.Hour))
+
+				Convey("Should count recent transitions per dashboard", func() {
+					query := m.GetDashboardsByOrgWithRecentAlertTransitionsQuery{OrgId: 1, Since: now.Add(-24 * time.Hour)}
+
+					err := GetDashboardsByOrgWithRecentAlertTransitions(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 2)
+					So(query.Result[0].DashboardId, ShouldEqual, busyDash.Id)
+					So(query.Result[0].TransitionCount, ShouldEqual, 2)
+					So(query.Result[0].LastTransitionAt.Unix(), ShouldEqual, now.Add(-time.Minute).Unix())
+					So(query.Result[1].DashboardId, ShouldEqual, quietDash.Id)
+					So(query.Result[1].TransitionCount, ShouldEqual, 1)
+				})
+
+				Convey("Should exclude older transitions", func() {
+					query := m.GetDashboardsByOrgWithRecentAlertTransitionsQuery{OrgId: 1, Since: now.Add(-time.Hour)}
+
+					err := GetDashboardsByOrgWithRecentAlertTransitions(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].DashboardId, ShouldEqual, busyDash.Id)
+				})
+			})
+
+			Convey("Given two dashboards, one is starred dashboard by user 10, other starred by user 1", func() {
+				starredDash := insertTestDashboard("starred dash", 1)
+				StarDashboard(&m.StarDashboardCommand{
+					DashboardId: starredDash.Id,
+					UserId:      10,
+				})
+
+				StarDashboard(&m.StarDashboardCommand{
+					DashboardId: savedDash.Id,
+					UserId:      1,
+				})
+
+				Convey("Should be able to search for starred dashboards", func() {
+					query := search.FindPersistedDashboardsQuery{OrgId: 1, UserId: 10, IsStarred: true}
+					err := SearchDashboards(&query)
+
+					So(err, ShouldBeNil)
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].Title, ShouldEqual, "starred dash")
+				})
+			})
+		})
+	})
+}
+
+func BenchmarkSearchDashboards(b *testing.B) {
+	InitTestDB(b)
+
+	for i := 0; i < 1000; i++ {
+		title, tags := fmt.Sprintf("apache %d", i), []interface{}{"staging"}
+		if i%10 == 0 {
+			title = fmt.Sprintf("nginx %d", i)
+		}
+		if i%2 == 0 {
+			tags = []interface{}{"prod"}
+		}
+
+		cmd := m.SaveDashboardCommand{
+			OrgId: 1,
+			Dashboard: simplejson.NewFromAny(map[string]interface{}{
+				"id":    nil,
+				"title": title,
+				"tags":  tags,
+			}),
+		}
+		if err := SaveDashboard(&cmd); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	// every nginx dashboard is tagged prod, the apache ones only match the tag
+	query := search.FindPersistedDashboardsQuery{Title: "nginx", Tags: []string{"prod"}, OrgId: 1}
+	if err := SearchDashboards(&query); err != nil {
+		b.Fatal(err)
+	}
+	if len(query.Result) != 100 {
+		b.Fatalf("expected 100 dashboards, got %d", len(query.Result))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := SearchDashboards(&query); err != nil {
+			b.Fatal(err)
+		}
+	}
 }
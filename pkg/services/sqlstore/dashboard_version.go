@@ -1,6 +1,8 @@
 package sqlstore
 
 import (
+	"context"
+	"fmt"
 	"strings"
 
 	"github.com/grafana/grafana/pkg/bus"
@@ -11,7 +13,9 @@ import (
 func init() {
 	bus.AddHandler("sql", GetDashboardVersion)
 	bus.AddHandler("sql", GetDashboardVersions)
+	bus.AddHandler("sql", GetDashboardsByVersionAndMessage)
 	bus.AddHandler("sql", DeleteExpiredVersions)
+	bus.AddHandler("sql", RestoreDashboardVersion)
 }
 
 // GetDashboardVersion gets the dashboard version for the given dashboard ID and version number.
@@ -34,6 +38,27 @@ func GetDashboardVersion(query *m.GetDashboardVersionQuery) error {
 	return nil
 }
 
+// GetDashboardsByVersionAndMessage gets the dashboard version for the given
+// dashboard ID and version number if its message contains the given fragment.
+func GetDashboardsByVersionAndMessage(query *m.GetDashboardsByVersionAndMessageQuery) error {
+	version := m.DashboardVersion{}
+	has, err := x.Where("dashboard_version.dashboard_id=? AND dashboard_version.version=? AND dashboard_version.message "+dialect.LikeStr()+" ? AND dashboard.org_id=?",
+		query.DashboardId, query.Version, "%"+query.MessageFragment+"%", query.OrgId).
+		Join("LEFT", "dashboard", `dashboard.id = dashboard_version.dashboard_id`).
+		Get(&version)
+
+	if err != nil {
+		return err
+	}
+
+	if !has {
+		return m.ErrDashboardVersionNotFound
+	}
+
+	query.Result = &version
+	return nil
+}
+
 // GetDashboardVersions gets all dashboard versions for the given dashboard ID.
 func GetDashboardVersions(query *m.GetDashboardVersionsQuery) error {
 	err := x.Table("dashboard_version").
@@ -44,8 +69,7 @@ func GetDashboardVersions(query *m.GetDashboardVersionsQuery) error {
 				dashboard_version.version,
 				dashboard_version.created,
 				dashboard_version.created_by as created_by_id,
-				dashboard_version.message,
-				dashboard_version.data,`+
+				dashboard_version.message,`+
 			dialect.Quote("user")+`.login as created_by`).
 		Join("LEFT", "user", `dashboard_version.created_by = `+dialect.Quote("user")+`.id`).
 		Join("LEFT", "dashboard", `dashboard.id = dashboard_version.dashboard_id`).
@@ -63,11 +87,53 @@ func GetDashboardVersions(query *m.GetDashboardVersionsQuery) error {
 	return nil
 }
 
+// RestoreDashboardVersion saves the data of a stored version as a new version
+// of the dashboard, leaving the existing history untouched.
+func RestoreDashboardVersion(cmd *m.RestoreDashboardVersionCommand) error {
+	return inTransaction(func(sess *DBSession) error {
+		var dashboard m.Dashboard
+		has, err := sess.Where("id=? AND org_id=?", cmd.DashboardId, cmd.OrgId).Get(&dashboard)
+		if err != nil {
+			return err
+		} else if !has {
+			return m.ErrDashboardNotFound
+		}
+
+		var version m.DashboardVersion
+		has, err = sess.Where("dashboard_id=? AND version=?", cmd.DashboardId, cmd.Version).Get(&version)
+		if err != nil {
+			return err
+		} else if !has {
+			return m.ErrDashboardVersionNotFound
+		}
+
+		saveCmd := m.SaveDashboardCommand{
+			OrgId:        cmd.OrgId,
+			UserId:       cmd.UserId,
+			RestoredFrom: version.Version,
+			Dashboard:    version.Data,
+			Message:      fmt.Sprintf("Restored from version %d", version.Version),
+		}
+		saveCmd.Dashboard.Set("id", dashboard.Id)
+		saveCmd.Dashboard.Set("version", dashboard.Version)
+
+		if err := saveDashboard(context.Background(), sess, &saveCmd); err != nil {
+			return err
+		}
+
+		cmd.Result = saveCmd.Result
+		return nil
+	})
+}
+
 func DeleteExpiredVersions(cmd *m.DeleteExpiredVersionsCommand) error {
 	return inTransaction(func(sess *DBSession) error {
 		expiredCount := int64(0)
 		versions := []DashboardVersionExp{}
 		versionsToKeep := setting.DashboardVersionsToKeep
+		if cmd.VersionsToKeep > 0 {
+			versionsToKeep = cmd.VersionsToKeep
+		}
 
 		if versionsToKeep < 1 {
 			versionsToKeep = 1
@@ -95,6 +161,7 @@ func DeleteExpiredVersions(cmd *m.DeleteExpiredVersionsCommand) error {
 				return err
 			}
 			expiredCount, _ = expiredResponse.RowsAffected()
+			cmd.DeletedRows = expiredCount
 			sqlog.Debug("Deleted old/expired dashboard versions", "expired", expiredCount)
 		}
 
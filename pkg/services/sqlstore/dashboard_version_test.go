@@ -67,6 +67,94 @@ func TestGetDashboardVersion(t *testing.T) {
 	})
 }
 
+func TestGetDashboardsByVersionAndMessage(t *testing.T) {
+	Convey("Testing dashboard version lookup by message", t, func() {
+		InitTestDB(t)
+		savedDash := insertTestDashboard("test dash 31", 1, "diff")
+
+		saveCmd := m.SaveDashboardCommand{
+			OrgId:     1,
+			Overwrite: true,
+			Message:   "ci build #1234",
+			Dashboard: simplejson.NewFromAny(map[string]interface{}{"id": savedDash.Id, "title": savedDash.Title}),
+		}
+		So(SaveDashboard(&saveCmd), ShouldBeNil)
+
+		Convey("Get the version with a matching message", func() {
+			query := m.GetDashboardsByVersionAndMessageQuery{DashboardId: savedDash.Id, OrgId: 1, Version: 2, MessageFragment: "#1234"}
+
+			err := GetDashboardsByVersionAndMessage(&query)
+			So(err, ShouldBeNil)
+			So(query.Result.Version, ShouldEqual, 2)
+			So(query.Result.Message, ShouldEqual, "ci build #1234")
+		})
+
+		Convey("Attempt to get a version with another message", func() {
+			query := m.GetDashboardsByVersionAndMessageQuery{DashboardId: savedDash.Id, OrgId: 1, Version: 2, MessageFragment: "#1235"}
+
+			err := GetDashboardsByVersionAndMessage(&query)
+			So(err, ShouldEqual, m.ErrDashboardVersionNotFound)
+		})
+
+		Convey("Attempt to get the version from another org", func() {
+			query := m.GetDashboardsByVersionAndMessageQuery{DashboardId: savedDash.Id, OrgId: 2, Version: 2, MessageFragment: "#1234"}
+
+			err := GetDashboardsByVersionAndMessage(&query)
+			So(err, ShouldEqual, m.ErrDashboardVersionNotFound)
+		})
+
+		Convey("Attempt to get another version with the same message", func() {
+			query := m.GetDashboardsByVersionAndMessageQuery{DashboardId: savedDash.Id, OrgId: 1, Version: 1, MessageFragment: "#1234"}
+
+			err := GetDashboardsByVersionAndMessage(&query)
+			So(err, ShouldEqual, m.ErrDashboardVersionNotFound)
+		})
+	})
+}
+
+func TestRestoreDashboardVersion(t *testing.T) {
+	Convey("Testing dashboard version restore", t, func() {
+		InitTestDB(t)
+		savedDash := insertTestDashboard("test dash 33", 1, "diff")
+		updateTestDashboard(savedDash, map[string]interface{}{"tags": "different-tag"})
+
+		Convey("Restoring a version saves its data as a new version", func() {
+			cmd := m.RestoreDashboardVersionCommand{DashboardId: savedDash.Id, OrgId: 1, UserId: 2, Version: 1}
+
+			err := RestoreDashboardVersion(&cmd)
+			So(err, ShouldBeNil)
+			So(cmd.Result.Version, ShouldEqual, 3)
+			So(cmd.Result.Data.Get("tags").MustStringArray(), ShouldResemble, []string{"diff"})
+
+			query := m.GetDashboardVersionQuery{DashboardId: savedDash.Id, OrgId: 1, Version: 3}
+			err = GetDashboardVersion(&query)
+			So(err, ShouldBeNil)
+			So(query.Result.RestoredFrom, ShouldEqual, 1)
+			So(query.Result.ParentVersion, ShouldEqual, 2)
+			So(query.Result.Message, ShouldEqual, "Restored from version 1")
+
+			versions := m.GetDashboardVersionsQuery{DashboardId: savedDash.Id, OrgId: 1}
+			err = GetDashboardVersions(&versions)
+			So(err, ShouldBeNil)
+			So(len(versions.Result), ShouldEqual, 3)
+		})
+
+		Convey("Restoring a version that does not exist fails", func() {
+			cmd := m.RestoreDashboardVersionCommand{DashboardId: savedDash.Id, OrgId: 1, Version: 7}
+
+			err := RestoreDashboardVersion(&cmd)
+			So(err, ShouldEqual, m.ErrDashboardVersionNotFound)
+		})
+
+		Convey("Restoring a version of a dashboard in another org fails", func() {
+			cmd := m.RestoreDashboardVersionCommand{DashboardId: savedDash.Id, OrgId: 2, Version: 1}
+
+			err := RestoreDashboardVersion(&cmd)
+			So(err, ShouldEqual, m.ErrDashboardNotFound)
+		})
+	})
+}
+
 func TestGetDashboardVersions(t *testing.T) {
 	Convey("Testing dashboard versions retrieval", t, func() {
 		InitTestDB(t)
@@ -100,6 +188,19 @@ func TestGetDashboardVersions(t *testing.T) {
 			So(err, ShouldBeNil)
 			So(len(query.Result), ShouldEqual, 2)
 		})
+
+		Convey("Get a page of versions, newest first", func() {
+			updateTestDashboard(savedDash, map[string]interface{}{"tags": "different-tag"})
+			updateTestDashboard(savedDash, map[string]interface{}{"tags": "another-tag"})
+
+			query := m.GetDashboardVersionsQuery{DashboardId: savedDash.Id, OrgId: 1, Limit: 2, Start: 1}
+			err := GetDashboardVersions(&query)
+
+			So(err, ShouldBeNil)
+			So(len(query.Result), ShouldEqual, 2)
+			So(query.Result[0].Version, ShouldEqual, 2)
+			So(query.Result[1].Version, ShouldEqual, 1)
+		})
 	})
 }
 
@@ -141,5 +242,25 @@ func TestDeleteExpiredVersions(t *testing.T) {
 
 			So(len(query.Result), ShouldEqual, versionsToWrite)
 		})
+
+		Convey("Clean up using the number of versions given in the command", func() {
+			for i := 0; i < 20; i++ {
+				updateTestDashboard(savedDash, map[string]interface{}{
+					"tags": "different-tag",
+				})
+			}
+
+			cmd := m.DeleteExpiredVersionsCommand{VersionsToKeep: 10}
+			err := DeleteExpiredVersions(&cmd)
+			So(err, ShouldBeNil)
+			So(cmd.DeletedRows, ShouldEqual, 20)
+
+			query := m.GetDashboardVersionsQuery{DashboardId: savedDash.Id, OrgId: 1}
+			GetDashboardVersions(&query)
+
+			So(len(query.Result), ShouldEqual, 10)
+			So(query.Result[0].Version, ShouldEqual, 30)
+			So(query.Result[9].Version, ShouldEqual, 21)
+		})
 	})
 }
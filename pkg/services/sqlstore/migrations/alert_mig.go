@@ -82,4 +82,8 @@ func addAlertMigrations(mg *Migrator) {
 		{Name: "type", Type: DB_NVarchar, Length: 255, Nullable: false},
 		{Name: "settings", Type: DB_Text, Nullable: false},
 	}))
+
+	mg.AddMigration("Add column silenced_until in alert", NewAddColumnMigration(alertV1, &Column{
+		Name: "silenced_until", Type: DB_DateTime, Nullable: true,
+	}))
 }
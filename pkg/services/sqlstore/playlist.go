@@ -2,9 +2,13 @@ package sqlstore
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/grafana/grafana/pkg/bus"
 	m "github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/search"
 )
 
 func init() {
@@ -14,6 +18,8 @@ func init() {
 	bus.AddHandler("sql", SearchPlaylists)
 	bus.AddHandler("sql", GetPlaylist)
 	bus.AddHandler("sql", GetPlaylistItem)
+	bus.AddHandler("sql", GetDashboardsByPlaylistId)
+	bus.AddHandler("sql", GetDashboardsInAnyPlaylist)
 }
 
 func CreatePlaylist(cmd *m.CreatePlaylistCommand) error {
@@ -159,3 +165,115 @@ func GetPlaylistItem(query *m.GetPlaylistItemsByIdQuery) error {
 
 	return err
 }
+
+// GetDashboardsByPlaylistId resolves the items of a playlist to dashboards,
+// in playlist order. Tag items are resolved with the dashboard search.
+func GetDashboardsByPlaylistId(query *m.GetDashboardsByPlaylistIdQuery) error {
+	if query.PlaylistId == 0 {
+		return m.ErrCommandValidationFailed
+	}
+
+	has, err := x.Where("id=? AND org_id=?", query.PlaylistId, query.OrgId).Get(&m.Playlist{})
+	if err != nil {
+		return err
+	} else if !has {
+		return m.ErrPlaylistNotFound
+	}
+
+	var playlistItems = make([]m.PlaylistItem, 0)
+	if err := x.Where("playlist_id=?", query.PlaylistId).Find(&playlistItems); err != nil {
+		return err
+	}
+
+	sort.SliceStable(playlistItems, func(i, j int) bool {
+		return playlistItems[i].Order < playlistItems[j].Order
+	})
+
+	dashboardIds := make([]int64, 0)
+	for _, item := range playlistItems {
+		switch item.Type {
+		case "dashboard_by_id":
+			if dashboardId, err := strconv.ParseInt(item.Value, 10, 64); err == nil {
+				dashboardIds = append(dashboardIds, dashboardId)
+			}
+		case "dashboard_by_tag":
+			searchQuery := search.FindPersistedDashboardsQuery{OrgId: query.OrgId, Tags: []string{item.Value}}
+			if err := SearchDashboards(&searchQuery); err != nil {
+				return err
+			}
+
+			for _, hit := range searchQuery.Result {
+				dashboardIds = append(dashboardIds, hit.Id)
+			}
+		}
+	}
+
+	query.Result = make([]*m.Dashboard, 0)
+	if len(dashboardIds) == 0 {
+		return nil
+	}
+
+	dashboards := make([]*m.Dashboard, 0)
+	if err := x.In("id", dashboardIds).Where("org_id=?", query.OrgId).Find(&dashboards); err != nil {
+		return err
+	}
+
+	byId := make(map[int64]*m.Dashboard)
+	for _, dash := range dashboards {
+		byId[dash.Id] = dash
+	}
+
+	for _, dashboardId := range dashboardIds {
+		if dash, exists := byId[dashboardId]; exists {
+			query.Result = append(query.Result, dash)
+			delete(byId, dashboardId)
+		}
+	}
+
+	return nil
+}
+
+func GetDashboardsInAnyPlaylist(query *m.GetDashboardsInAnyPlaylistQuery) error {
+	var playlistItems = make([]m.PlaylistItem, 0)
+	err := x.Table("playlist_item").
+		Join("INNER", "playlist", "playlist.id = playlist_item.playlist_id").
+		Where("playlist.org_id=?", query.OrgId).
+		Find(&playlistItems)
+	if err != nil {
+		return err
+	}
+
+	dashboardIds := make([]interface{}, 0)
+	tags := make([]interface{}, 0)
+	for _, item := range playlistItems {
+		switch item.Type {
+		case "dashboard_by_id":
+			if dashboardId, err := strconv.ParseInt(item.Value, 10, 64); err == nil {
+				dashboardIds = append(dashboardIds, dashboardId)
+			}
+		case "dashboard_by_tag":
+			tags = append(tags, item.Value)
+		}
+	}
+
+	query.Result = make([]*m.Dashboard, 0)
+
+	filters := make([]string, 0)
+	params := []interface{}{query.OrgId}
+	if len(dashboardIds) > 0 {
+		filters = append(filters, "id IN (?"+strings.Repeat(",?", len(dashboardIds)-1)+")")
+		params = append(params, dashboardIds...)
+	}
+	if len(tags) > 0 {
+		filters = append(filters, "id IN (SELECT dashboard_id FROM dashboard_tag WHERE term IN (?"+strings.Repeat(",?", len(tags)-1)+"))")
+		params = append(params, tags...)
+	}
+
+	if len(filters) == 0 {
+		return nil
+	}
+
+	return x.Where("org_id=? AND ("+strings.Join(filters, " OR ")+")", params...).
+		Asc("title").
+		Find(&query.Result)
+}
@@ -1,6 +1,7 @@
 package sqlstore
 
 import (
+	"strconv"
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
@@ -32,6 +33,63 @@ func TestPlaylistDataAccess(t *testing.T) {
 
 				So(err, ShouldBeNil)
 
+				Convey("can get dashboards of playlist", func() {
+					graphiteDash := insertTestDashboard("graphite", 1, "graphite")
+					influxDash := insertTestDashboard("influxdb", 1, "influxdb")
+					otherInfluxDash := insertTestDashboard("influxdb 2", 1, "influxdb")
+
+					items := []m.PlaylistItemDTO{
+						{Title: "graphite", Value: strconv.FormatInt(graphiteDash.Id, 10), Type: "dashboard_by_id"},
+						{Title: "influxdb", Value: "influxdb", Type: "dashboard_by_tag"},
+						{Title: "influxdb again", Value: strconv.FormatInt(influxDash.Id, 10), Type: "dashboard_by_id"},
+					}
+					err := UpdatePlaylist(&m.UpdatePlaylistCommand{Name: "NYC office", OrgId: 1, Id: 1, Interval: "10s", Items: items})
+					So(err, ShouldBeNil)
+
+					query := m.GetDashboardsByPlaylistIdQuery{PlaylistId: 1, OrgId: 1}
+					err = GetDashboardsByPlaylistId(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 3)
+					So(query.Result[0].Id, ShouldEqual, graphiteDash.Id)
+					So(query.Result[1].Id, ShouldEqual, influxDash.Id)
+					So(query.Result[2].Id, ShouldEqual, otherInfluxDash.Id)
+
+					Convey("but not from another org", func() {
+						query := m.GetDashboardsByPlaylistIdQuery{PlaylistId: 1, OrgId: 2}
+						err = GetDashboardsByPlaylistId(&query)
+						So(err, ShouldEqual, m.ErrPlaylistNotFound)
+					})
+				})
+
+				Convey("can get dashboards that are in any playlist", func() {
+					graphiteDash := insertTestDashboard("graphite", 1, "graphite")
+					influxDash := insertTestDashboard("influxdb", 1, "influxdb")
+					insertTestDashboard("not in a playlist", 1, "mysql")
+					insertTestDashboard("other org influxdb", 2, "influxdb")
+
+					items := []m.PlaylistItemDTO{
+						{Title: "graphite", Value: strconv.FormatInt(graphiteDash.Id, 10), Type: "dashboard_by_id"},
+						{Title: "influxdb", Value: "influxdb", Type: "dashboard_by_tag"},
+					}
+					err := CreatePlaylist(&m.CreatePlaylistCommand{Name: "Stockholm office", Interval: "10s", OrgId: 1, Items: items})
+					So(err, ShouldBeNil)
+
+					items = []m.PlaylistItemDTO{
+						{Title: "graphite again", Value: strconv.FormatInt(graphiteDash.Id, 10), Type: "dashboard_by_id"},
+					}
+					err = CreatePlaylist(&m.CreatePlaylistCommand{Name: "Oslo office", Interval: "10s", OrgId: 1, Items: items})
+					So(err, ShouldBeNil)
+
+					query := m.GetDashboardsInAnyPlaylistQuery{OrgId: 1}
+					err = GetDashboardsInAnyPlaylist(&query)
+					So(err, ShouldBeNil)
+
+					So(len(query.Result), ShouldEqual, 2)
+					So(query.Result[0].Id, ShouldEqual, graphiteDash.Id)
+					So(query.Result[1].Id, ShouldEqual, influxDash.Id)
+				})
+
 				Convey("can remove playlist", func() {
 					query := m.DeletePlaylistCommand{Id: 1}
 					err = DeletePlaylist(&query)
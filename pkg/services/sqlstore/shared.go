@@ -1,6 +1,7 @@
 package sqlstore
 
 import (
+	"context"
 	"time"
 
 	"github.com/go-xorm/xorm"
@@ -25,12 +26,23 @@ func newSession() *DBSession {
 }
 
 func inTransaction(callback dbTransactionFunc) error {
-	return inTransactionWithRetry(callback, 0)
+	return inTransactionWithRetry(context.Background(), callback, 0)
 }
 
-func inTransactionWithRetry(callback dbTransactionFunc, retry int) error {
+// inTransactionCtx works like inTransaction but does not start or commit the
+// transaction once ctx is cancelled. Statements are not bound to ctx, so
+// callbacks should check ctx between statements as well
+func inTransactionCtx(ctx context.Context, callback dbTransactionFunc) error {
+	return inTransactionWithRetry(ctx, callback, 0)
+}
+
+func inTransactionWithRetry(ctx context.Context, callback dbTransactionFunc, retry int) error {
 	var err error
 
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
 	sess := newSession()
 	defer sess.Close()
 
@@ -40,13 +52,17 @@ func inTransactionWithRetry(callback dbTransactionFunc, retry int) error {
 
 	err = callback(sess)
 
+	if err == nil {
+		err = ctx.Err()
+	}
+
 	// special handling of database locked errors for sqlite, then we can retry 3 times
 	if sqlError, ok := err.(sqlite3.Error); ok && retry < 5 {
 		if sqlError.Code == sqlite3.ErrLocked {
 			sess.Rollback()
 			time.Sleep(time.Millisecond * time.Duration(10))
 			sqlog.Info("Database table locked, sleeping then retrying", "retry", retry)
-			return inTransactionWithRetry(callback, retry+1)
+			return inTransactionWithRetry(ctx, callback, retry+1)
 		}
 	}
 
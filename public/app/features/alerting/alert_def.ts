@@ -31,9 +31,15 @@ var alertStateSortScore = {
 var evalFunctions = [
   {text: 'IS ABOVE', value: 'gt'},
   {text: 'IS BELOW', value: 'lt'},
+  {text: 'IS ABOVE ANY OF', value: 'gt_multi'},
+  {text: 'IS BELOW ANY OF', value: 'lt_multi'},
+  {text: 'IS EQUAL TO', value: 'eq'},
+  {text: 'IS NOT EQUAL TO', value: 'ne'},
   {text: 'IS OUTSIDE RANGE', value: 'outside_range'},
   {text: 'IS WITHIN RANGE', value: 'within_range'},
-  {text: 'HAS NO VALUE' , value: 'no_value'}
+  {text: 'CHANGED BY MORE THAN %', value: 'percent_diff'},
+  {text: 'HAS NO VALUE' , value: 'no_value'},
+  {text: 'HAS VALUE' , value: 'has_value'}
 ];
 
 var evalOperators = [
@@ -347,7 +347,10 @@ export class AlertTabCtrl {
     // ensure params array is correct length
     switch (evaluator.type) {
       case "lt":
-        case "gt": {
+        case "gt":
+        case "eq":
+        case "ne":
+        case "percent_diff": {
         evaluator.params = [evaluator.params[0]];
         break;
       }
@@ -356,7 +359,14 @@ export class AlertTabCtrl {
         evaluator.params = [evaluator.params[0], evaluator.params[1]];
         break;
       }
-      case "no_value": {
+      case "gt_multi":
+        case "lt_multi": {
+        // keep all thresholds, multi threshold rules need at least one
+        evaluator.params = evaluator.params.length ? evaluator.params : [null];
+        break;
+      }
+      case "no_value":
+        case "has_value": {
         evaluator.params = [];
       }
     }
@@ -364,6 +374,20 @@ export class AlertTabCtrl {
     this.evaluatorParamsChanged();
   }
 
+  isMultiThreshold(evaluator) {
+    return evaluator.type === 'gt_multi' || evaluator.type === 'lt_multi';
+  }
+
+  addThreshold(evaluator) {
+    evaluator.params.push(null);
+    this.evaluatorParamsChanged();
+  }
+
+  removeThreshold(evaluator, index) {
+    evaluator.params.splice(index, 1);
+    this.evaluatorParamsChanged();
+  }
+
   clearHistory() {
     appEvents.emit('confirm-modal', {
       title: 'Delete Alert History',
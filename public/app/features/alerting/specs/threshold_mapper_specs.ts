@@ -25,6 +25,31 @@ describe('ThresholdMapper', () => {
     });
   });
 
+  describe('with multi threshold evaluator', () => {
+    it('can mapp every threshold', () => {
+      var panel: any = {
+        type: 'graph',
+        alert: {
+          conditions: [
+            {
+              type: 'query',
+              evaluator: { type: 'gt_multi', params: [90, 95], }
+            }
+          ]
+        }
+      };
+
+      var updated = ThresholdMapper.alertToGraphThresholds(panel);
+      expect(updated).to.be(true);
+      expect(panel.thresholds.length).to.be(2);
+      expect(panel.thresholds[0].op).to.be('gt');
+      expect(panel.thresholds[0].value).to.be(90);
+
+      expect(panel.thresholds[1].op).to.be('gt');
+      expect(panel.thresholds[1].value).to.be(95);
+    });
+  });
+
   describe('with outside range evaluator', () => {
     it('can mapp query conditions to thresholds', () => {
       var panel: any = {
@@ -26,6 +26,14 @@ export class ThresholdMapper {
           thresholds.push({value: value, op: 'lt'});
           break;
         }
+        case "gt_multi":
+        case "lt_multi": {
+          let op = evaluator.type === 'gt_multi' ? 'gt' : 'lt';
+          for (let value of evaluator.params) {
+            thresholds.push({value: value, op: op});
+          }
+          break;
+        }
         case "outside_range": {
           let value1 = evaluator.params[0];
           let value2 = evaluator.params[1];